@@ -21,6 +21,16 @@ type PolyEncodingParams struct {
 	PolyBase    int     // PolyCiphertext polynomial encoding base
 	FPScaleBase int     // fixed point encoding scale base
 	FPPrecision float64 // min error tolerance for fixed point encoding
+	DegreeBound int     // max number of coefficients in an encoding (0 for the default of 128)
+}
+
+// degreeBound returns the configured degree bound or the default one
+func (params *PolyEncodingParams) degreeBound() int {
+	if params.DegreeBound > 0 {
+		return params.DegreeBound
+	}
+
+	return defaultDegreeBound
 }
 
 // PublicKey is the BGN public key used for encryption
@@ -31,12 +41,18 @@ type PublicKey struct {
 	Q  *pbc.Element // generator of subgroup H
 	N  *big.Int     // order of the elliptic curve group
 
+	// DecryptionVK is e(P, P)^q1, used to verify proofs of correct decryption.
+	// Every key (including keys from RunDKG) publishes it along with P and Q
+	DecryptionVK *pbc.Element
+
 	MsgSpace      *big.Int     // valid message space for decryption (will not try to decrypt values beyond this range)
 	Pairing       *pbc.Pairing // pairing between G1 and GT
 	PairingParams string
 	Deterministic bool // whether or not the homomorphic operations are deterministic
 
 	PolyEncodingParams *PolyEncodingParams // message encoding parameters
+	degreeTable        []*big.Int          // powers of the polynomial base
+	degreeSumTable     []*big.Int          // partial sums of the powers of the polynomial base
 	mu                 sync.Mutex          // mutex for parallel executions (pbc is not thread-safe)
 }
 
@@ -48,6 +64,8 @@ type publicKeyWrapper struct {
 	Q  []byte
 	N  *big.Int
 
+	DecryptionVK []byte
+
 	MsgSpace           *big.Int
 	PairingParams      string
 	Deterministic      bool
@@ -118,12 +136,16 @@ func NewKeyGen(keyBits int, msgSpace *big.Int, polyBase int, fpScaleBase int, fp
 	Q.PowBig(P, R)
 	Q.PowBig(Q, q2)
 
+	// commitment to the secret key in GT
+	vk := pairing.NewGT().Pair(P, P)
+	vk.PowBig(vk, q1)
+
 	polyParams := &PolyEncodingParams{
-		polyBase, fpScaleBase, fpPrecision,
+		polyBase, fpScaleBase, fpPrecision, defaultDegreeBound,
 	}
 
 	// create public key with the generated groups
-	pk := &PublicKey{G1, P, Q, n, msgSpace, pairing, paramsString, deterministic, polyParams, sync.Mutex{}}
+	pk := &PublicKey{G1, P, Q, n, vk, msgSpace, pairing, paramsString, deterministic, polyParams, nil, nil, sync.Mutex{}}
 
 	// create secret key
 	sk := &SecretKey{q1, R, polyBase}
@@ -203,19 +225,20 @@ func (pk *PublicKey) SetupDecryption(sk *SecretKey) {
 // Decrypt uses the secret key to recover the encrypted value
 // throws an error if decryption fails
 func (sk *SecretKey) Decrypt(ct *Ciphertext, pk *PublicKey) (*big.Int, error) {
-	return sk.decrypt(ct, pk, false)
+	return sk.decrypt(ct, pk, 1, false)
 }
 
 // DecryptFailSafe returns zero if encryption fails rather than throwing an error
 func (sk *SecretKey) DecryptFailSafe(ct *Ciphertext, pk *PublicKey) *big.Int {
-	v, err := sk.decrypt(ct, pk, false)
+	v, err := sk.decrypt(ct, pk, 1, false)
 	if err != nil {
 		return big.NewInt(0)
 	}
 	return v
 }
 
-func (sk *SecretKey) decrypt(ct *Ciphertext, pk *PublicKey, failed bool) (*big.Int, error) {
+// decrypt searches for the plaintext in a range window times larger than the message space
+func (sk *SecretKey) decrypt(ct *Ciphertext, pk *PublicKey, window int64, failed bool) (*big.Int, error) {
 	gsk := pk.G1.NewFieldElement()
 	csk := ct.C.NewFieldElement()
 
@@ -228,13 +251,13 @@ func (sk *SecretKey) decrypt(ct *Ciphertext, pk *PublicKey, failed bool) (*big.I
 		gsk.PowBig(gsk, sk.Key)
 	}
 
-	pt, err := pk.recoverMessage(gsk, csk, ct.L2)
+	pt, err := pk.recoverMessage(gsk, csk, ct.L2, window)
 
 	// if the decryption failed, then try decrypting
 	// the inverse of the element as it encodes a negative value
 	if err != nil && !failed {
 		neg := pk.Neg(ct)
-		dec, err := sk.decrypt(neg, pk, true)
+		dec, err := sk.decrypt(neg, pk, window, true)
 		if err != nil {
 			return nil, err
 		}
@@ -249,13 +272,18 @@ func (sk *SecretKey) decrypt(ct *Ciphertext, pk *PublicKey, failed bool) (*big.I
 	return pt, nil
 }
 
-// MultConst multiplies an encrypted value by a constant
+// MultConst multiplies an encrypted value by a constant.
+// The constant may be negative or larger than N; it is reduced
+// mod N before exponentiation and is never modified
 func (pk *PublicKey) MultConst(c *Ciphertext, constant *big.Int) *Ciphertext {
 
+	// canonical representative of the constant in [0, N)
+	k := new(big.Int).Mod(constant, pk.N)
+
 	// handle the case of L1 and L2 ciphertext seperately
 	if !c.L2 {
 		res := c.C.NewFieldElement()
-		res.PowBig(c.C, constant)
+		res.PowBig(c.C, k)
 
 		if !pk.Deterministic {
 			r := newCryptoRandom(pk.N)
@@ -274,7 +302,7 @@ func (pk *PublicKey) MultConst(c *Ciphertext, constant *big.Int) *Ciphertext {
 	res := pk.Pairing.NewGT().NewFieldElement()
 	pk.mu.Unlock()
 
-	res.PowBig(c.C, constant)
+	res.PowBig(c.C, k)
 
 	if !pk.Deterministic {
 		r := newCryptoRandom(pk.N)
@@ -313,6 +341,177 @@ func (pk *PublicKey) Mult(ct1 *Ciphertext, ct2 *Ciphertext) *Ciphertext {
 	return &Ciphertext{res, true}
 }
 
+// MultWithRandomness multiplies two encrypted values together
+// and re-randomizes the level2 result using the provided randomness r
+func (pk *PublicKey) MultWithRandomness(ct1 *Ciphertext, ct2 *Ciphertext, r *big.Int) *Ciphertext {
+
+	pk.mu.Lock()
+	res := pk.Pairing.NewGT().NewFieldElement()
+	pair := pk.Pairing.NewGT().Pair(pk.Q, pk.Q)
+	pk.mu.Unlock()
+
+	res.Pair(ct1.C, ct2.C)
+	pair.PowBig(pair, r)
+	res.Mul(res, pair)
+
+	return &Ciphertext{res, true}
+}
+
+// RerandomizeWithRandomness blinds the ciphertext with Q^r
+// (or e(Q, Q)^r for level2 ciphertexts) using the provided randomness r
+func (pk *PublicKey) RerandomizeWithRandomness(ct *Ciphertext, r *big.Int) *Ciphertext {
+
+	h := pk.rerandomizationBase(ct.L2)
+	h.PowBig(h, r)
+
+	res := ct.C.NewFieldElement()
+	res.Mul(ct.C, h)
+
+	return &Ciphertext{res, ct.L2}
+}
+
+// rerandomizationBase returns a copy of Q for level1
+// and e(Q, Q) for level2 ciphertexts
+func (pk *PublicKey) rerandomizationBase(l2 bool) *pbc.Element {
+
+	pk.mu.Lock()
+	defer pk.mu.Unlock()
+
+	if l2 {
+		return pk.Pairing.NewGT().Pair(pk.Q, pk.Q)
+	}
+
+	h := pk.G1.NewFieldElement()
+	return h.Set(pk.Q)
+}
+
+// InnerProduct homomorphically computes the inner product of two vectors
+// of level1 ciphertexts and returns the result as a level2 ciphertext.
+// The pairings are evaluated in parallel and the result is re-randomized
+// only once rather than after every addition
+func (pk *PublicKey) InnerProduct(a []*Ciphertext, b []*Ciphertext) *Ciphertext {
+
+	if len(a) != len(b) {
+		panic("Attempting to compute inner product of vectors of different lengths")
+	}
+
+	for i := range a {
+		if a[i].L2 || b[i].L2 {
+			panic("Attempting to multiply level2 ciphertexts")
+		}
+	}
+
+	if len(a) == 0 {
+		return pk.rerandomize(pk.makeL2(pk.encryptZero()))
+	}
+
+	terms := make([]*pbc.Element, len(a))
+
+	var wg sync.WaitGroup
+	for i := range a {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			pk.mu.Lock()
+			term := pk.Pairing.NewGT().NewFieldElement()
+			pk.mu.Unlock()
+
+			term.Pair(a[i].C, b[i].C)
+			terms[i] = term
+		}(i)
+	}
+	wg.Wait()
+
+	return pk.rerandomize(&Ciphertext{productOf(terms), true})
+}
+
+// InnerProductPlain homomorphically computes the inner product of a vector
+// of ciphertexts with a vector of plaintext weights. The result is at the
+// same level as the ciphertexts (level1 ciphertexts are moved to level2
+// if the vector contains both) and is re-randomized only once
+func (pk *PublicKey) InnerProductPlain(a []*Ciphertext, w []*big.Int) *Ciphertext {
+
+	if len(a) != len(w) {
+		panic("Attempting to compute inner product of vectors of different lengths")
+	}
+
+	l2 := false
+	for _, ct := range a {
+		l2 = l2 || ct.L2
+	}
+
+	if len(a) == 0 {
+		return pk.rerandomize(pk.encryptZero())
+	}
+
+	terms := make([]*pbc.Element, len(a))
+
+	var wg sync.WaitGroup
+	for i := range a {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			ct := a[i]
+			if l2 && !ct.L2 {
+				pk.mu.Lock()
+				ct = pk.makeL2(ct)
+				pk.mu.Unlock()
+			}
+
+			term := ct.C.NewFieldElement()
+			term.PowBig(ct.C, new(big.Int).Mod(w[i], pk.N))
+			terms[i] = term
+		}(i)
+	}
+	wg.Wait()
+
+	return pk.rerandomize(&Ciphertext{productOf(terms), l2})
+}
+
+// productOf returns the product of the (non-empty) list of group elements
+func productOf(elems []*pbc.Element) *pbc.Element {
+	res := elems[0].NewFieldElement()
+	res.Set(elems[0])
+
+	for _, e := range elems[1:] {
+		res.Mul(res, e)
+	}
+
+	return res
+}
+
+// rerandomize blinds the ciphertext with a fresh encryption of zero
+// unless the public key specifies deterministic operations
+func (pk *PublicKey) rerandomize(ct *Ciphertext) *Ciphertext {
+
+	if pk.Deterministic {
+		return ct
+	}
+
+	r := newCryptoRandom(pk.N)
+	res := ct.C.NewFieldElement()
+
+	if ct.L2 {
+		pk.mu.Lock()
+		pair := pk.Pairing.NewGT().Pair(pk.Q, pk.Q)
+		pk.mu.Unlock()
+
+		pair.PowBig(pair, r)
+		res.Mul(ct.C, pair)
+		return &Ciphertext{res, true}
+	}
+
+	pk.mu.Lock()
+	h := pk.G1.NewFieldElement()
+	h.PowBig(pk.Q, r)
+	pk.mu.Unlock()
+
+	res.Mul(ct.C, h)
+	return &Ciphertext{res, false}
+}
+
 func (pk *PublicKey) makeL2(ct *Ciphertext) *Ciphertext {
 	result := pk.Pairing.NewGT().NewFieldElement()
 	result.Pair(ct.C, pk.EncryptDeterministic(big.NewInt(1)).C)
@@ -354,7 +553,7 @@ func (pk *PublicKey) EncryptWithRandomness(x *big.Int, r *big.Int) *Ciphertext {
 
 // RecoverMessage finds the discrete logarithm to recover and returns the value (if found)
 // if the value is too large, an error is thrown
-func (pk *PublicKey) recoverMessage(gsk *pbc.Element, csk *pbc.Element, l2 bool) (*big.Int, error) {
+func (pk *PublicKey) recoverMessage(gsk *pbc.Element, csk *pbc.Element, l2 bool, window int64) (*big.Int, error) {
 
 	zero := gsk.NewFieldElement()
 
@@ -362,7 +561,7 @@ func (pk *PublicKey) recoverMessage(gsk *pbc.Element, csk *pbc.Element, l2 bool)
 		return big.NewInt(0), nil
 	}
 
-	m, err := pk.getDL(csk, gsk, l2)
+	m, err := pk.getDL(csk, gsk, l2, window)
 
 	if err != nil {
 		return nil, err
@@ -408,7 +607,7 @@ func (pk *PublicKey) Sub(a *Ciphertext, b *Ciphertext) *Ciphertext {
 
 		pair.PowBig(pair, r)
 		result.Mul(result, pair)
-		return &Ciphertext{result, false}
+		return &Ciphertext{result, true}
 
 	}
 
@@ -512,13 +711,9 @@ func (pk *PublicKey) NewCiphertextFromBytes(data []byte) (*Ciphertext, error) {
 		return nil, err
 	}
 
-	var elem *pbc.Element
-	if w.L2 {
-		elem = pk.Pairing.NewGT().Pair(pk.Q, pk.Q)
-		elem.SetBytes(w.CBytes)
-	} else {
-		elem = pk.G1.NewFieldElement()
-		elem.SetBytes(w.CBytes)
+	elem, err := pk.decodeElement(w.CBytes, w.L2)
+	if err != nil {
+		return nil, err
 	}
 
 	return NewCiphertext(elem, w.L2), nil
@@ -526,7 +721,9 @@ func (pk *PublicKey) NewCiphertextFromBytes(data []byte) (*Ciphertext, error) {
 }
 
 // NewPolyCiphertextFromBytes generates a poly ciphertext from marshalled poly ciphertext.
-// Requires the public key in order to ensure the correct pairing is used
+// Requires the public key in order to ensure the correct pairing is used.
+// The coefficient bound is taken from the data as is: it can't be checked against
+// the encrypted coefficients, so it is only as trustworthy as the sender
 func (pk *PublicKey) NewPolyCiphertextFromBytes(data []byte) (*PolyCiphertext, error) {
 
 	if len(data) == 0 {
@@ -541,22 +738,53 @@ func (pk *PublicKey) NewPolyCiphertextFromBytes(data []byte) (*PolyCiphertext, e
 		return nil, err
 	}
 
+	if w.Bound != nil && w.Bound.Sign() < 0 {
+		return nil, errors.New("coefficient bound must not be negative")
+	}
+
+	if w.Denominator != nil && w.Denominator.Sign() <= 0 {
+		return nil, errors.New("denominator must be positive")
+	}
+
 	coeffs := make([]*Ciphertext, 0)
 	for _, coeffBytes := range w.CoeffBytes {
 
-		var elem *pbc.Element
-		if w.L2 {
-			elem = pk.Pairing.NewGT().Pair(pk.Q, pk.Q)
-			elem.SetBytes(coeffBytes)
-		} else {
-			elem = pk.G1.NewFieldElement()
-			elem.SetBytes(coeffBytes)
+		elem, err := pk.decodeElement(coeffBytes, w.L2)
+		if err != nil {
+			return nil, err
 		}
 
 		coeffs = append(coeffs, NewCiphertext(elem, w.L2))
 	}
 
-	return NewPolyCiphertext(coeffs, w.Degree, w.ScaleFactor, w.L2), nil
+	ct := NewPolyCiphertext(coeffs, w.Degree, w.ScaleFactor, w.L2)
+	ct.Bound = w.Bound
+	ct.Denominator = w.Denominator
+
+	return ct, nil
+}
+
+// decodeElement decodes an element of G1 (or GT if l2) and checks that it
+// lies in the subgroup of order N. pbc accepts any point on the curve,
+// which has a cofactor, so points with a small order component could
+// otherwise slip through checks that reduce exponents mod N
+func (pk *PublicKey) decodeElement(data []byte, l2 bool) (*pbc.Element, error) {
+
+	var elem *pbc.Element
+	if l2 {
+		elem = pk.Pairing.NewGT()
+	} else {
+		elem = pk.G1.NewFieldElement()
+	}
+	elem.SetBytes(data)
+
+	test := elem.NewFieldElement()
+	test.PowBig(elem, pk.N)
+	if !test.Is1() {
+		return nil, errors.New("element is not in the subgroup of order N")
+	}
+
+	return elem, nil
 }
 
 func (pk *PublicKey) encryptZero() *Ciphertext {
@@ -600,8 +828,14 @@ func (pk *PublicKey) MarshalBinary() ([]byte, error) {
 		return []byte(""), nil
 	}
 
+	var vk []byte
+	if pk.DecryptionVK != nil {
+		vk = pk.DecryptionVK.Bytes()
+	}
+
 	// wrap struct
 	w := publicKeyWrapper{
+		DecryptionVK:       vk,
 		G1:                 pk.G1.Bytes(),
 		P:                  pk.P.Bytes(),
 		Q:                  pk.Q.Bytes(),
@@ -652,6 +886,12 @@ func (pk *PublicKey) UnmarshalBinary(data []byte) error {
 	Q := G1.NewFieldElement()
 	Q.SetBytes(w.Q)
 
+	if len(w.DecryptionVK) > 0 {
+		vk := pairing.NewGT()
+		vk.SetBytes(w.DecryptionVK)
+		pk.DecryptionVK = vk
+	}
+
 	pk.G1 = G1
 	pk.P = P
 	pk.Q = Q
@@ -662,5 +902,9 @@ func (pk *PublicKey) UnmarshalBinary(data []byte) error {
 	pk.PolyEncodingParams = w.PolyEncodingParams
 	pk.PairingParams = w.PairingParams
 
+	if pk.PolyEncodingParams != nil {
+		pk.computeEncodingTable()
+	}
+
 	return nil
 }
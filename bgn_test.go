@@ -1,8 +1,11 @@
 package bgn
 
 import (
+	"bytes"
 	"math/big"
 	"testing"
+
+	"github.com/Nik-U/pbc"
 )
 
 const KEYBITS = 512
@@ -19,10 +22,20 @@ func TestMarshalUnmarshalPublicKey(t *testing.T) {
 		t.Fatalf("%v", err)
 	}
 
-	bytes, _ := pk.MarshalBinary()
+	data, _ := pk.MarshalBinary()
+
+	recovered := &PublicKey{}
+	recovered.UnmarshalBinary(data)
+
+	if !bytes.Equal(recovered.DecryptionVK.Bytes(), pk.DecryptionVK.Bytes()) {
+		t.Fatalf("Incorrect recovery of the decryption verification key\n")
+	}
 
-	pk = &PublicKey{}
-	pk.UnmarshalBinary(bytes)
+	// the recovered key can encode without regenerating a key
+	pt, _ := recovered.NewPolyPlaintext(big.NewFloat(42))
+	if pt.PolyEval().Cmp(big.NewFloat(42)) != 0 {
+		t.Errorf("Expected: 42 got: %v", pt.PolyEval())
+	}
 }
 
 func TestMarshalUnmarshalPublicKeyNil(t *testing.T) {
@@ -65,9 +78,9 @@ func TestPolyCiphertextToFromBytes(t *testing.T) {
 		t.Fatalf("%v", err)
 	}
 
-	m := pk.NewPolyPlaintext(big.NewFloat(2.99))
+	m, _ := pk.NewPolyPlaintext(big.NewFloat(2.99))
 
-	expected := pk.EncryptPoly(m)
+	expected, _ := pk.DivPoly(pk.EncryptPoly(m), big.NewInt(7))
 	bytes, err := expected.Bytes()
 	if err != nil {
 		t.Fatalf("Error when encoding ciphertext to bytes %v\n", err.Error())
@@ -82,6 +95,265 @@ func TestPolyCiphertextToFromBytes(t *testing.T) {
 	if expected.String() != recovered.String() {
 		t.Fatalf("Incorrect recovery.Expected %v, got %v\n", expected, recovered)
 	}
+
+	if recovered.Bound.Cmp(expected.Bound) != 0 {
+		t.Fatalf("Incorrect recovery of the coefficient bound. Expected %v, got %v\n", expected.Bound, recovered.Bound)
+	}
+
+	if recovered.Denominator.Cmp(expected.Denominator) != 0 {
+		t.Fatalf("Incorrect recovery of the denominator. Expected %v, got %v\n", expected.Denominator, recovered.Denominator)
+	}
+}
+
+func TestPolyCiphertextFromBytesMalformed(t *testing.T) {
+
+	pk, _, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	m, _ := pk.NewPolyPlaintext(big.NewFloat(2.99))
+
+	ct := pk.EncryptPoly(m)
+	ct.Bound = big.NewInt(-1)
+
+	data, _ := ct.Bytes()
+	if _, err := pk.NewPolyCiphertextFromBytes(data); err == nil {
+		t.Errorf("PolyCiphertext with a negative bound accepted\n")
+	}
+
+	for _, denominator := range []int64{0, -7} {
+		ct = pk.EncryptPoly(m)
+		ct.Denominator = big.NewInt(denominator)
+
+		data, _ = ct.Bytes()
+		if _, err := pk.NewPolyCiphertextFromBytes(data); err == nil {
+			t.Errorf("PolyCiphertext with denominator %d accepted\n", denominator)
+		}
+	}
+}
+
+// outOfSubgroupElement returns an element of G1 whose order does not divide N.
+// With pbc all zero bytes decode to (0, 0) which has order 2 on y^2 = x^3 + x
+func outOfSubgroupElement(t *testing.T, pk *PublicKey) *pbc.Element {
+	t.Helper()
+
+	data := make([]byte, pk.G1.BytesLen())
+	for i := 0; i < 256; i++ {
+		data[len(data)-1] = byte(i)
+
+		elem := pk.G1.NewFieldElement()
+		elem.SetBytes(data)
+
+		test := elem.NewFieldElement()
+		test.PowBig(elem, pk.N)
+		if !test.Is1() {
+			return elem
+		}
+	}
+
+	t.Fatalf("No element outside the subgroup found\n")
+	return nil
+}
+
+func TestCiphertextFromBytesOutOfSubgroup(t *testing.T) {
+
+	pk, _, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	ct := pk.Encrypt(big.NewInt(5))
+	ct.C.Mul(ct.C, outOfSubgroupElement(t, pk))
+
+	data, _ := ct.Bytes()
+	if _, err := pk.NewCiphertextFromBytes(data); err == nil {
+		t.Errorf("Ciphertext outside the subgroup of order N accepted\n")
+	}
+
+	pct := NewPolyCiphertext([]*Ciphertext{pk.Encrypt(big.NewInt(1)), ct}, 2, 0, false)
+	data, _ = pct.Bytes()
+	if _, err := pk.NewPolyCiphertextFromBytes(data); err == nil {
+		t.Errorf("PolyCiphertext outside the subgroup of order N accepted\n")
+	}
+}
+
+func TestMultConstNegative(t *testing.T) {
+
+	// negative results are decrypted through Neg, which rerandomizes unless deterministic
+	for _, det := range []bool{true, false} {
+
+		pk, sk, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, det)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		pk.SetupDecryption(sk)
+
+		c := pk.Encrypt(big.NewInt(7))
+		constant := big.NewInt(-3)
+
+		actual, err := sk.Decrypt(pk.MultConst(c, constant), pk)
+		if err != nil {
+			t.Fatalf("[L1 det=%v] Error when decrypting %v\n", det, err.Error())
+		}
+
+		if actual.Cmp(big.NewInt(-21)) != 0 {
+			t.Errorf("[L1 det=%v] Expected: -21 got: %v\n", det, actual)
+		}
+
+		product := pk.MultConst(pk.Mult(c, pk.Encrypt(big.NewInt(2))), constant)
+		if !pk.Neg(product).L2 {
+			t.Errorf("[L2 det=%v] Negation lost the ciphertext level\n", det)
+		}
+
+		actual, err = sk.Decrypt(product, pk)
+		if err != nil {
+			t.Fatalf("[L2 det=%v] Error when decrypting %v\n", det, err.Error())
+		}
+
+		if actual.Cmp(big.NewInt(-42)) != 0 {
+			t.Errorf("[L2 det=%v] Expected: -42 got: %v\n", det, actual)
+		}
+
+		if constant.Cmp(big.NewInt(-3)) != 0 {
+			t.Errorf("Constant was modified: %v\n", constant)
+		}
+	}
+}
+
+func TestMultConstLarge(t *testing.T) {
+
+	pk, sk, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	pk.SetupDecryption(sk)
+
+	c := pk.Encrypt(big.NewInt(7))
+
+	// 5 + 3N and -5 - 3N are congruent to 5 and -5 mod N
+	large := new(big.Int).Add(big.NewInt(5), new(big.Int).Mul(pk.N, big.NewInt(3)))
+	negLarge := new(big.Int).Neg(large)
+
+	actual, err := sk.Decrypt(pk.MultConst(c, large), pk)
+	if err != nil {
+		t.Fatalf("[L1] Error when decrypting %v\n", err.Error())
+	}
+
+	if actual.Cmp(big.NewInt(35)) != 0 {
+		t.Errorf("[L1] Expected: 35 got: %v\n", actual)
+	}
+
+	actual, err = sk.Decrypt(pk.MultConst(c, negLarge), pk)
+	if err != nil {
+		t.Fatalf("[L1] Error when decrypting %v\n", err.Error())
+	}
+
+	if actual.Cmp(big.NewInt(-35)) != 0 {
+		t.Errorf("[L1] Expected: -35 got: %v\n", actual)
+	}
+
+	l2 := pk.Mult(c, pk.Encrypt(big.NewInt(1)))
+
+	actual, err = sk.Decrypt(pk.MultConst(l2, large), pk)
+	if err != nil {
+		t.Fatalf("[L2] Error when decrypting %v\n", err.Error())
+	}
+
+	if actual.Cmp(big.NewInt(35)) != 0 {
+		t.Errorf("[L2] Expected: 35 got: %v\n", actual)
+	}
+
+	actual, err = sk.Decrypt(pk.MultConst(l2, negLarge), pk)
+	if err != nil {
+		t.Fatalf("[L2] Error when decrypting %v\n", err.Error())
+	}
+
+	if actual.Cmp(big.NewInt(-35)) != 0 {
+		t.Errorf("[L2] Expected: -35 got: %v\n", actual)
+	}
+}
+
+func TestInnerProduct(t *testing.T) {
+
+	pk, sk, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	pk.SetupDecryption(sk)
+
+	x := []int64{1, 2, 3, 4}
+	y := []int64{5, -6, 7, 8}
+
+	a := make([]*Ciphertext, len(x))
+	b := make([]*Ciphertext, len(y))
+	for i := range x {
+		a[i] = pk.Encrypt(big.NewInt(x[i]))
+		b[i] = pk.Encrypt(big.NewInt(y[i]))
+	}
+
+	res := pk.InnerProduct(a, b)
+	if !res.L2 {
+		t.Fatalf("Expected a level2 ciphertext\n")
+	}
+
+	actual, err := sk.Decrypt(res, pk)
+	if err != nil {
+		t.Fatalf("Error when decrypting %v\n", err.Error())
+	}
+
+	// 5 - 12 + 21 + 32
+	if actual.Cmp(big.NewInt(46)) != 0 {
+		t.Errorf("Expected: 46 got: %v\n", actual)
+	}
+}
+
+func TestInnerProductPlain(t *testing.T) {
+
+	pk, sk, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	pk.SetupDecryption(sk)
+
+	x := []int64{1, 2, 3, 4}
+	w := []*big.Int{big.NewInt(5), big.NewInt(-6), big.NewInt(7), big.NewInt(8)}
+
+	a := make([]*Ciphertext, len(x))
+	for i := range x {
+		a[i] = pk.Encrypt(big.NewInt(x[i]))
+	}
+
+	res := pk.InnerProductPlain(a, w)
+	if res.L2 {
+		t.Fatalf("Expected a level1 ciphertext\n")
+	}
+
+	actual, err := sk.Decrypt(res, pk)
+	if err != nil {
+		t.Fatalf("[L1] Error when decrypting %v\n", err.Error())
+	}
+
+	if actual.Cmp(big.NewInt(46)) != 0 {
+		t.Errorf("[L1] Expected: 46 got: %v\n", actual)
+	}
+
+	// mixing levels moves the result to level2
+	a[0] = pk.Mult(a[0], pk.Encrypt(big.NewInt(1)))
+
+	res = pk.InnerProductPlain(a, w)
+	if !res.L2 {
+		t.Fatalf("Expected a level2 ciphertext\n")
+	}
+
+	actual, err = sk.Decrypt(res, pk)
+	if err != nil {
+		t.Fatalf("[L2] Error when decrypting %v\n", err.Error())
+	}
+
+	if actual.Cmp(big.NewInt(46)) != 0 {
+		t.Errorf("[L2] Expected: 46 got: %v\n", actual)
+	}
 }
 
 func BenchmarkKeyGen(b *testing.B) {
@@ -138,3 +410,21 @@ func BenchmarkMult(b *testing.B) {
 		pk.Mult(c, c)
 	}
 }
+
+func BenchmarkInnerProduct(b *testing.B) {
+	pk, _, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		panic(err)
+	}
+
+	v := make([]*Ciphertext, 16)
+	for i := range v {
+		v[i] = pk.Encrypt(big.NewInt(1))
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		pk.InnerProduct(v, v)
+	}
+}
@@ -3,6 +3,7 @@ package bgn
 import (
 	"bytes"
 	"encoding/gob"
+	"math/big"
 
 	"github.com/Nik-U/pbc"
 )
@@ -28,6 +29,8 @@ type PolyCiphertext struct {
 	Degree       int           // degree of the polynomial s
 	ScaleFactor  int           // scaling factor for fixed-point encoding
 	L2           bool          // indicates whether ciphertext is atlevel2
+	Bound        *big.Int      // bound on the magnitude of the encrypted coefficients (nil if not tracked)
+	Denominator  *big.Int      // exact divisor applied to the value after decryption (nil for 1)
 }
 
 type polyCiphertextWrapper struct {
@@ -35,16 +38,19 @@ type polyCiphertextWrapper struct {
 	Degree      int
 	ScaleFactor int
 	L2          bool
+	Bound       *big.Int
+	Denominator *big.Int
 }
 
 // Copy returns a copy of the given ciphertext
 func (ct *PolyCiphertext) Copy() *PolyCiphertext {
-	return &PolyCiphertext{ct.Coefficients, ct.Degree, ct.ScaleFactor, ct.L2}
+	return &PolyCiphertext{ct.Coefficients, ct.Degree, ct.ScaleFactor, ct.L2, ct.Bound, ct.Denominator}
 }
 
-// NewPolyCiphertext generates a new polynmial ciphertext with specified coefficients and parameters
+// NewPolyCiphertext generates a new polynmial ciphertext with specified coefficients and parameters.
+// The coefficient bound is not tracked for such ciphertexts
 func NewPolyCiphertext(coefficients []*Ciphertext, degree int, scaleFactor int, l2 bool) *PolyCiphertext {
-	return &PolyCiphertext{coefficients, degree, scaleFactor, l2}
+	return &PolyCiphertext{coefficients, degree, scaleFactor, l2, nil, nil}
 }
 
 // NewCiphertext generates a BGN ciphertext with specified coefficients and parameters
@@ -104,6 +110,8 @@ func (ct *PolyCiphertext) Bytes() ([]byte, error) {
 	w.L2 = ct.L2
 	w.Degree = ct.Degree
 	w.ScaleFactor = ct.ScaleFactor
+	w.Bound = ct.Bound
+	w.Denominator = ct.Denominator
 
 	// use default gob encoder
 	var buf bytes.Buffer
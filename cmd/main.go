@@ -26,10 +26,18 @@ func runPolyArithmeticCheck(keyBits int, messageSpace *big.Int, polyBase int, fp
 	pk, sk, _ := bgn.NewKeyGen(keyBits, messageSpace, polyBase, fpScaleBase, fpPrecision, true)
 	bgn.ComputeDecryptionPreprocessing(pk, sk)
 
-	m1 := pk.NewPolyPlaintext(big.NewFloat(0.0111))
-	m2 := pk.NewPolyPlaintext(big.NewFloat(9.1))
-	m3 := pk.NewPolyPlaintext(big.NewFloat(2.75))
-	m4 := pk.NewPolyPlaintext(big.NewFloat(2.99))
+	encode := func(v *big.Float) *bgn.PolyPlaintext {
+		pt, err := pk.NewPolyPlaintext(v)
+		if err != nil {
+			panic(err)
+		}
+		return pt
+	}
+
+	m1 := encode(big.NewFloat(0.0111))
+	m2 := encode(big.NewFloat(9.1))
+	m3 := encode(big.NewFloat(2.75))
+	m4 := encode(big.NewFloat(2.99))
 
 	c1 := pk.EncryptPoly(m1)
 	c2 := pk.EncryptPoly(m2)
@@ -37,35 +45,50 @@ func runPolyArithmeticCheck(keyBits int, messageSpace *big.Int, polyBase int, fp
 	c4 := pk.EncryptPoly(m4)
 	c6 := pk.NegPoly(c4)
 
+	decrypt := func(ct *bgn.PolyCiphertext) *bgn.PolyPlaintext {
+		pt, err := sk.DecryptPoly(ct, pk)
+		if err != nil {
+			panic(err)
+		}
+		return pt
+	}
+
+	check := func(ct *bgn.PolyCiphertext, err error) *bgn.PolyCiphertext {
+		if err != nil {
+			panic(err)
+		}
+		return ct
+	}
+
 	print("\n----------RUNNING ARITHMETIC TEST----------\n\n")
 
-	fmt.Printf("c1 = E(%s)\n", sk.DecryptPoly(c1, pk).String())
-	fmt.Printf("c2 = E(%s)\n", sk.DecryptPoly(c2, pk).String())
-	fmt.Printf("c3 = E(%s)\n", sk.DecryptPoly(c3, pk).String())
-	fmt.Printf("c4 = E(%s)\n", sk.DecryptPoly(c4, pk).String())
+	fmt.Printf("c1 = E(%s)\n", decrypt(c1).String())
+	fmt.Printf("c2 = E(%s)\n", decrypt(c2).String())
+	fmt.Printf("c3 = E(%s)\n", decrypt(c3).String())
+	fmt.Printf("c4 = E(%s)\n", decrypt(c4).String())
 	fmt.Println()
 
-	r1 := pk.AddPoly(c1, c4)
-	fmt.Printf("[Add] E(%s) ⊞ E(%s) = E(%s)\n\n", m1, m4, sk.DecryptPoly(r1, pk).String())
+	r1 := check(pk.AddPoly(c1, c4))
+	fmt.Printf("[Add] E(%s) ⊞ E(%s) = E(%s)\n\n", m1, m4, decrypt(r1).String())
 
 	const1 := big.NewFloat(10.0)
-	r2 := pk.MultConstPoly(c2, const1)
-	fmt.Printf("[MultConst] E(%s) ⊠ %f = E(%s)\n\n", m2, const1, sk.DecryptPoly(r2, pk).String())
+	r2 := check(pk.MultConstPoly(c2, const1))
+	fmt.Printf("[MultConst] E(%s) ⊠ %f = E(%s)\n\n", m2, const1, decrypt(r2).String())
 
-	r3 := pk.MultPoly(c3, c4)
-	dr3 := sk.DecryptPoly(r3, pk)
-	fmt.Printf("[Mult] E(%s) ⊠ E(%s) = E(%s)\n\n", m3, m4, sk.DecryptPoly(r3, pk).String())
+	r3 := check(pk.MultPoly(c3, c4))
+	dr3 := decrypt(r3)
+	fmt.Printf("[Mult] E(%s) ⊠ E(%s) = E(%s)\n\n", m3, m4, decrypt(r3).String())
 
 	const2 := big.NewFloat(0.5)
-	r4 := pk.MultConstPoly(r3, const2)
-	dr4 := sk.DecryptPoly(r4, pk)
+	r4 := check(pk.MultConstPoly(r3, const2))
+	dr4 := decrypt(r4)
 	fmt.Printf("[MultConst] E(%s) ⊠ %f = E(%s)\n\n", dr3.String(), const2, dr4.String())
 
-	r5 := pk.AddPoly(r3, r3)
-	fmt.Printf("[Add] E(%s) ⊞ E(%s) = E(%s)\n\n", dr3.String(), dr3.String(), sk.DecryptPoly(r5, pk).String())
+	r5 := check(pk.AddPoly(r3, r3))
+	fmt.Printf("[Add] E(%s) ⊞ E(%s) = E(%s)\n\n", dr3.String(), dr3.String(), decrypt(r5).String())
 
-	r6 := pk.AddPoly(c1, c6)
-	fmt.Printf("[Add] E(%s) ⊞ Neg(E(%s)) = E(%s)\n\n", m1, m4, sk.DecryptPoly(r6, pk).String())
+	r6 := check(pk.AddPoly(c1, c6))
+	fmt.Printf("[Add] E(%s) ⊞ Neg(E(%s)) = E(%s)\n\n", m1, m4, decrypt(r6).String())
 
 	fmt.Println("\n----------DONE----------")
 
@@ -0,0 +1,650 @@
+package bgn
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/Nik-U/pbc"
+)
+
+// number of repetitions of the biprimality test
+// (each repetition catches a composite modulus with probability >= 1/2)
+const biprimalityTests = 40
+
+// bound on the small primes used to sieve candidate moduli
+const trialDivisionBound = 2000
+
+// DKGConfig holds the public parameters of the distributed key generation
+type DKGConfig struct {
+	Parties       int      // number of parties (at least 3)
+	Threshold     int      // number of parties required to decrypt
+	KeyBits       int      // approximate bit length of N
+	MsgSpace      *big.Int // valid message space for decryption
+	PolyBase      int      // PolyCiphertext polynomial encoding base
+	FPScaleBase   int      // fixed point encoding scale base
+	FPPrecision   float64  // min error tolerance for fixed point encoding
+	DegreeBound   int      // max number of coefficients in an encoding (0 for the default)
+	Deterministic bool     // whether or not the homomorphic operations are deterministic
+}
+
+// DKGResult is the output of the distributed key generation for a single party.
+// Each party instantiates its own pairing so elements of different results
+// cannot be mixed and must be exchanged as bytes
+type DKGResult struct {
+	Pk       *PublicKey
+	Params   *ThresholdParams
+	KeyShare *ThresholdKeyShare // Shamir share of q1 mod N
+	PShare   *big.Int           // additive share of q1
+	QShare   *big.Int           // additive share of q2
+}
+
+// DKGNetwork is an in-process simulated network connecting the parties
+// of the distributed key generation. Messages are delivered in rounds
+type DKGNetwork struct {
+	parties int
+	inbox   []chan *dkgMessage
+	pending [][]*dkgMessage // out-of-round messages (owned by the receiving party)
+
+	done chan struct{}
+	once sync.Once
+}
+
+type dkgMessage struct {
+	Round  int
+	From   int
+	Values []*big.Int
+	Data   [][]byte
+	Params string
+}
+
+type dkgParty struct {
+	cfg   *DKGConfig
+	index int
+	net   *DKGNetwork
+	round int
+	prime *big.Int // modulus of the field used for BGW multiplication
+}
+
+var errDKGAborted = errors.New("distributed key generation aborted")
+
+// NewDKGNetwork creates a simulated network for the given number of parties
+func NewDKGNetwork(parties int) *DKGNetwork {
+
+	inbox := make([]chan *dkgMessage, parties)
+	for i := range inbox {
+		// parties are at most one round apart
+		inbox[i] = make(chan *dkgMessage, 2*parties)
+	}
+
+	return &DKGNetwork{
+		parties: parties,
+		inbox:   inbox,
+		pending: make([][]*dkgMessage, parties),
+		done:    make(chan struct{}),
+	}
+}
+
+func (net *DKGNetwork) abort() {
+	net.once.Do(func() { close(net.done) })
+}
+
+func (net *DKGNetwork) send(to int, msg *dkgMessage) bool {
+	select {
+	case net.inbox[to-1] <- msg:
+		return true
+	case <-net.done:
+		return false
+	}
+}
+
+// receive blocks until one message from every party has been
+// received for the given round and returns them ordered by sender
+func (net *DKGNetwork) receive(to int, round int) ([]*dkgMessage, error) {
+
+	msgs := make([]*dkgMessage, 0, net.parties)
+
+	stash := net.pending[to-1][:0]
+	for _, msg := range net.pending[to-1] {
+		if msg.Round == round {
+			msgs = append(msgs, msg)
+		} else {
+			stash = append(stash, msg)
+		}
+	}
+	net.pending[to-1] = stash
+
+	for len(msgs) < net.parties {
+		select {
+		case msg := <-net.inbox[to-1]:
+			if msg.Round == round {
+				msgs = append(msgs, msg)
+			} else {
+				net.pending[to-1] = append(net.pending[to-1], msg)
+			}
+		case <-net.done:
+			return nil, errDKGAborted
+		}
+	}
+
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].From < msgs[j].From })
+
+	return msgs, nil
+}
+
+// RunDKG generates a BGN key pair without a trusted dealer using the
+// Boneh-Franklin distributed RSA modulus generation: the parties sample
+// additive shares of q1 and q2, compute N = q1*q2 using BGW multiplication
+// and run a distributed biprimality test until N is a product of two primes.
+// The additive shares of q1 are then converted to Shamir shares for
+// threshold decryption. Each party runs in its own goroutine and
+// communicates over a DKGNetwork; the outputs of all parties are returned.
+// Note: the additional check of the full Boneh-Franklin test that rules out
+// the rare moduli which always pass the main test is omitted
+func RunDKG(cfg *DKGConfig) ([]*DKGResult, error) {
+
+	if cfg.Parties < 3 {
+		return nil, errors.New("distributed key generation requires at least 3 parties")
+	}
+
+	if cfg.Threshold < 1 || cfg.Threshold > cfg.Parties {
+		return nil, errors.New("threshold must be between 1 and the number of parties")
+	}
+
+	if cfg.KeyBits < 16 || cfg.KeyBits%2 != 0 {
+		return nil, errors.New("key bits must be >= 16 and divisible by 2")
+	}
+
+	// each prime is at least 2^(KeyBits/2 - 1)
+	if cfg.MsgSpace.BitLen() >= cfg.KeyBits/2-1 {
+		return nil, errors.New("message space is greater than the group order")
+	}
+
+	// the BGW field must be large enough to hold N without wrap around
+	// (each prime is less than 2^(KeyBits/2 + 1))
+	prime, err := rand.Prime(rand.Reader, cfg.KeyBits+3)
+	if err != nil {
+		return nil, err
+	}
+
+	net := NewDKGNetwork(cfg.Parties)
+	results := make([]*DKGResult, cfg.Parties)
+	errs := make([]error, cfg.Parties)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= cfg.Parties; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := &dkgParty{cfg, i, net, 0, prime}
+			results[i-1], errs[i-1] = p.run()
+			if errs[i-1] != nil {
+				net.abort()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && err != errDKGAborted {
+			return nil, err
+		}
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// exchange sends msgFor(j) to every party j (including itself)
+// and returns the messages received from all parties in this round
+func (p *dkgParty) exchange(msgFor func(to int) *dkgMessage) ([]*dkgMessage, error) {
+
+	p.round++
+	for to := 1; to <= p.cfg.Parties; to++ {
+		msg := *msgFor(to)
+		msg.Round = p.round
+		msg.From = p.index
+		if !p.net.send(to, &msg) {
+			return nil, errDKGAborted
+		}
+	}
+
+	return p.net.receive(p.index, p.round)
+}
+
+func (p *dkgParty) broadcast(msg *dkgMessage) ([]*dkgMessage, error) {
+	return p.exchange(func(int) *dkgMessage { return msg })
+}
+
+func (p *dkgParty) run() (*DKGResult, error) {
+
+	for {
+		pShare, qShare, err := p.newPrimeShares()
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := p.computeModulus(pShare, qShare)
+		if err != nil {
+			return nil, err
+		}
+
+		// all parties reach the same decision since N is public
+		if !passesTrialDivision(n) {
+			continue
+		}
+
+		ok, err := p.biprimalityTest(n, pShare, qShare)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			return p.finish(n, pShare, qShare)
+		}
+	}
+}
+
+// newPrimeShares samples additive shares such that the sums are 3 mod 4:
+// party 1 holds a share which is 3 mod 4 and the other shares are 0 mod 4
+func (p *dkgParty) newPrimeShares() (*big.Int, *big.Int, error) {
+
+	k := uint(p.cfg.KeyBits / 2)
+
+	sample := func() (*big.Int, error) {
+		if p.index == 1 {
+			// [2^(k-1), 2^k) so that the sum has at least k bits
+			max := new(big.Int).Lsh(big.NewInt(1), k-1)
+			r, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, err
+			}
+			r.Add(r, max)
+			r.SetBit(r, 0, 1)
+			return r.SetBit(r, 1, 1), nil
+		}
+
+		max := new(big.Int).Lsh(big.NewInt(1), k-3)
+		max.Div(max, big.NewInt(int64(p.cfg.Parties)))
+		r, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return nil, err
+		}
+		return r.Lsh(r, 2), nil
+	}
+
+	pShare, err := sample()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	qShare, err := sample()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pShare, qShare, nil
+}
+
+// computeModulus computes N = (sum p_i)(sum q_i) using BGW: each party
+// Shamir-shares its p_i and q_i, locally multiplies the sums of its shares
+// and the products are interpolated at zero
+func (p *dkgParty) computeModulus(pShare, qShare *big.Int) (*big.Int, error) {
+
+	degree := (p.cfg.Parties - 1) / 2
+
+	pPoly := newSharingPolynomial(pShare, degree, p.prime)
+	qPoly := newSharingPolynomial(qShare, degree, p.prime)
+
+	msgs, err := p.exchange(func(to int) *dkgMessage {
+		x := big.NewInt(int64(to))
+		return &dkgMessage{Values: []*big.Int{
+			evalPolynomial(pPoly, x, p.prime),
+			evalPolynomial(qPoly, x, p.prime),
+		}}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pSum := big.NewInt(0)
+	qSum := big.NewInt(0)
+	for _, msg := range msgs {
+		pSum.Add(pSum, msg.Values[0])
+		qSum.Add(qSum, msg.Values[1])
+	}
+
+	prod := new(big.Int).Mul(pSum, qSum)
+	prod.Mod(prod, p.prime)
+
+	msgs, err = p.broadcast(&dkgMessage{Values: []*big.Int{prod}})
+	if err != nil {
+		return nil, err
+	}
+
+	indices := make([]int, len(msgs))
+	for i, msg := range msgs {
+		indices[i] = msg.From
+	}
+
+	n := big.NewInt(0)
+	for i, msg := range msgs {
+		lambda, err := lagrangeCoefficient(indices, i, p.prime)
+		if err != nil {
+			return nil, err
+		}
+		n.Add(n, lambda.Mul(lambda, msg.Values[0]))
+	}
+
+	return n.Mod(n, p.prime), nil
+}
+
+// biprimalityTest runs the Boneh-Franklin test: for random g with
+// Jacobi symbol 1, g^((N-p-q+1)/4) = g^(phi(N)/4) must be +1 or -1 mod N
+func (p *dkgParty) biprimalityTest(n, pShare, qShare *big.Int) (bool, error) {
+
+	exp := new(big.Int).Add(pShare, qShare)
+	if p.index == 1 {
+		exp.Sub(new(big.Int).Add(n, big.NewInt(1)), exp)
+	}
+	exp.Rsh(exp, 2)
+
+	values := make([]*big.Int, biprimalityTests)
+	for t := 0; t < biprimalityTests; t++ {
+		values[t] = new(big.Int).Exp(biprimalityBase(n, t), exp, n)
+	}
+
+	msgs, err := p.broadcast(&dkgMessage{Values: values})
+	if err != nil {
+		return false, err
+	}
+
+	for t := 0; t < biprimalityTests; t++ {
+		prod := big.NewInt(1)
+		for _, msg := range msgs[1:] {
+			prod.Mul(prod, msg.Values[t])
+			prod.Mod(prod, n)
+		}
+
+		v := msgs[0].Values[t]
+		if v.Cmp(prod) != 0 && v.Cmp(new(big.Int).Sub(n, prod)) != 0 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// finish generates the pairing parameters and generators for the modulus
+// and converts the additive shares of q1 into Shamir shares mod N
+func (p *dkgParty) finish(n, pShare, qShare *big.Int) (*DKGResult, error) {
+
+	// party 1 generates the pairing parameters for everyone
+	paramsString := ""
+	if p.index == 1 {
+		paramsString = pbc.GenerateA1(n).String()
+	}
+
+	msgs, err := p.broadcast(&dkgMessage{Params: paramsString})
+	if err != nil {
+		return nil, err
+	}
+	paramsString = msgs[0].Params
+
+	params, err := pbc.NewParamsFromString(paramsString)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := parseLFromPBCParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	pairing := pbc.NewPairing(params)
+	G1 := pairing.NewG1()
+
+	P := hashToSubgroup(G1, "P", paramsString, l)
+	U := hashToSubgroup(G1, "Q", paramsString, l)
+
+	// Q = U^q2 = prod_i U^(q_i) has order q1
+	uq := G1.NewFieldElement()
+	uq.PowBig(U, qShare)
+
+	msgs, err = p.broadcast(&dkgMessage{Data: [][]byte{uq.Bytes()}})
+	if err != nil {
+		return nil, err
+	}
+
+	Q := G1.NewFieldElement()
+	for i, msg := range msgs {
+		elem := G1.NewFieldElement()
+		elem.SetBytes(msg.Data[0])
+		if i == 0 {
+			Q.Set(elem)
+		} else {
+			Q.Mul(Q, elem)
+		}
+	}
+
+	polyParams := &PolyEncodingParams{
+		p.cfg.PolyBase, p.cfg.FPScaleBase, p.cfg.FPPrecision, p.cfg.DegreeBound,
+	}
+
+	pk := &PublicKey{G1, P, Q, n, nil, p.cfg.MsgSpace, pairing, paramsString, p.cfg.Deterministic, polyParams, nil, nil, sync.Mutex{}}
+	pk.computeEncodingTable()
+
+	tp, share, err := p.shareKey(pk, pShare)
+	if err != nil {
+		return nil, err
+	}
+
+	// e(P, P)^q1 is interpolated from the verification keys
+	partials := make([]*PartialDecryption, tp.Threshold)
+	for i := 0; i < tp.Threshold; i++ {
+		partials[i] = &PartialDecryption{Index: i + 1, D: tp.VerificationKeys[i], L2: true}
+	}
+
+	pk.DecryptionVK, err = interpolateInExponent(partials, n)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DKGResult{pk, tp, share, pShare, qShare}, nil
+}
+
+// shareKey re-shares the additive share of q1 using Feldman verifiable
+// secret sharing in GT so that the sum of the received shares is a
+// Shamir share of q1 mod N with publicly computable verification keys.
+// The resulting shares are only ever applied to elements of GT (see
+// PartialDecryption), so no party publishes a power of a G1 element by its share
+func (p *dkgParty) shareKey(pk *PublicKey, pShare *big.Int) (*ThresholdParams, *ThresholdKeyShare, error) {
+
+	t := p.cfg.Threshold
+
+	V := pk.Pairing.NewGT().Pair(pk.P, pk.P)
+	poly := newSharingPolynomial(new(big.Int).Mod(pShare, pk.N), t-1, pk.N)
+
+	commitments := make([][]byte, t)
+	for k, coeff := range poly {
+		c := V.NewFieldElement()
+		c.PowBig(V, coeff)
+		commitments[k] = c.Bytes()
+	}
+
+	msgs, err := p.exchange(func(to int) *dkgMessage {
+		return &dkgMessage{
+			Values: []*big.Int{evalPolynomial(poly, big.NewInt(int64(to)), pk.N)},
+			Data:   commitments,
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// commitments[i][k] = V^(a_ik) for the polynomial of party i+1
+	received := make([][]*pbc.Element, len(msgs))
+	for i, msg := range msgs {
+		if len(msg.Data) != t {
+			return nil, nil, fmt.Errorf("party %d sent %d commitments", msg.From, len(msg.Data))
+		}
+
+		received[i] = make([]*pbc.Element, t)
+		for k, data := range msg.Data {
+			c := pk.Pairing.NewGT()
+			c.SetBytes(data)
+			received[i][k] = c
+		}
+	}
+
+	vks := make([]*pbc.Element, p.cfg.Parties)
+	for j := 1; j <= p.cfg.Parties; j++ {
+		vk := V.NewFieldElement()
+		for i := range received {
+			term := feldmanCommitment(received[i], big.NewInt(int64(j)), pk.N)
+			if i == 0 {
+				vk.Set(term)
+			} else {
+				vk.Mul(vk, term)
+			}
+		}
+		vks[j-1] = vk
+	}
+
+	share := big.NewInt(0)
+	for i, msg := range msgs {
+		expected := feldmanCommitment(received[i], big.NewInt(int64(p.index)), pk.N)
+		actual := V.NewFieldElement()
+		actual.PowBig(V, msg.Values[0])
+
+		if !actual.Equals(expected) {
+			return nil, nil, fmt.Errorf("invalid key share received from party %d", msg.From)
+		}
+
+		share.Add(share, msg.Values[0])
+	}
+	share.Mod(share, pk.N)
+
+	tp := &ThresholdParams{
+		Threshold:        t,
+		Parties:          p.cfg.Parties,
+		V:                V,
+		VerificationKeys: vks,
+	}
+
+	return tp, &ThresholdKeyShare{p.index, share}, nil
+}
+
+// feldmanCommitment computes prod_k C_k^(x^k) = V^f(x)
+func feldmanCommitment(commitments []*pbc.Element, x *big.Int, n *big.Int) *pbc.Element {
+
+	res := commitments[0].NewFieldElement()
+	res.Set(commitments[0])
+
+	pow := big.NewInt(1)
+	for _, c := range commitments[1:] {
+		pow.Mul(pow, x)
+		pow.Mod(pow, n)
+
+		term := c.NewFieldElement()
+		term.PowBig(c, pow)
+		res.Mul(res, term)
+	}
+
+	return res
+}
+
+// newSharingPolynomial returns the coefficients of a random polynomial
+// of the given degree over Z_mod with constant term secret
+func newSharingPolynomial(secret *big.Int, degree int, mod *big.Int) []*big.Int {
+
+	coeffs := make([]*big.Int, degree+1)
+	coeffs[0] = new(big.Int).Set(secret)
+	for i := 1; i <= degree; i++ {
+		coeffs[i] = newCryptoRandom(mod)
+	}
+
+	return coeffs
+}
+
+// evalPolynomial evaluates the polynomial at x mod mod using Horner's method
+func evalPolynomial(coeffs []*big.Int, x *big.Int, mod *big.Int) *big.Int {
+
+	res := big.NewInt(0)
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		res.Mul(res, x)
+		res.Add(res, coeffs[i])
+		res.Mod(res, mod)
+	}
+
+	return res
+}
+
+// biprimalityBase derives the public base of the t-th biprimality test
+// from N such that its Jacobi symbol mod N is 1
+func biprimalityBase(n *big.Int, t int) *big.Int {
+
+	for ctr := uint32(0); ; ctr++ {
+		h := sha256.New()
+		h.Write(n.Bytes())
+
+		buf := make([]byte, 8)
+		binary.BigEndian.PutUint32(buf, uint32(t))
+		binary.BigEndian.PutUint32(buf[4:], ctr)
+		h.Write(buf)
+
+		g := new(big.Int).SetBytes(h.Sum(nil))
+		g.Mod(g, n)
+
+		if big.Jacobi(g, n) == 1 {
+			return g
+		}
+	}
+}
+
+// small odd primes used to sieve candidate moduli
+var smallPrimes = func() []*big.Int {
+	primes := make([]*big.Int, 0)
+	for d := int64(3); d < trialDivisionBound; d += 2 {
+		if big.NewInt(d).ProbablyPrime(0) {
+			primes = append(primes, big.NewInt(d))
+		}
+	}
+	return primes
+}()
+
+// passesTrialDivision returns false if N has a small prime factor
+func passesTrialDivision(n *big.Int) bool {
+
+	r := new(big.Int)
+	for _, d := range smallPrimes {
+		if r.Mod(n, d).Sign() == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hashToSubgroup deterministically maps the label to an element
+// of the order N subgroup of G1
+func hashToSubgroup(G1 *pbc.Element, label string, params string, l *big.Int) *pbc.Element {
+
+	h := sha256.Sum256([]byte(label + "\n" + params))
+
+	elem := G1.NewFieldElement()
+	elem.SetFromHash(h[:])
+	elem.PowBig(elem, new(big.Int).Mul(l, big.NewInt(4)))
+
+	return elem
+}
@@ -0,0 +1,160 @@
+package bgn
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/Nik-U/pbc"
+)
+
+func newDKGConfig(parties int, threshold int) *DKGConfig {
+	return &DKGConfig{
+		Parties:       parties,
+		Threshold:     threshold,
+		KeyBits:       KEYBITS,
+		MsgSpace:      big.NewInt(MSGSPACE),
+		PolyBase:      POLYBASE,
+		FPScaleBase:   FPSCALEBASE,
+		FPPrecision:   FPPREC,
+		Deterministic: DET,
+	}
+}
+
+// combinerKey deserializes the public key and threshold parameters of the
+// parties into a single pairing, checking that every party agrees on them
+func combinerKey(t *testing.T, results []*DKGResult) (*PublicKey, *ThresholdParams) {
+	t.Helper()
+
+	data, err := results[0].Pk.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	pk := &PublicKey{}
+	if err := pk.UnmarshalBinary(data); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	tp := &ThresholdParams{
+		Threshold:        results[0].Params.Threshold,
+		Parties:          results[0].Params.Parties,
+		V:                pk.Pairing.NewGT().SetBytes(results[0].Params.V.Bytes()),
+		VerificationKeys: make([]*pbc.Element, results[0].Params.Parties),
+	}
+
+	for i, vk := range results[0].Params.VerificationKeys {
+		tp.VerificationKeys[i] = pk.Pairing.NewGT().SetBytes(vk.Bytes())
+	}
+
+	for _, res := range results {
+		for i, vk := range res.Params.VerificationKeys {
+			if !bytes.Equal(vk.Bytes(), tp.VerificationKeys[i].Bytes()) {
+				t.Fatalf("Party %d disagrees on verification key %d\n", res.KeyShare.Index, i+1)
+			}
+		}
+	}
+
+	return pk, tp
+}
+
+func TestDKGModulus(t *testing.T) {
+
+	results, err := RunDKG(newDKGConfig(3, 2))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// all parties agree on the public key
+	for _, res := range results[1:] {
+		if res.Pk.N.Cmp(results[0].Pk.N) != 0 || !bytes.Equal(res.Pk.Q.Bytes(), results[0].Pk.Q.Bytes()) {
+			t.Fatalf("Parties disagree on the public key\n")
+		}
+	}
+
+	// reconstruct the factorization (only possible with every party's share)
+	q1 := big.NewInt(0)
+	q2 := big.NewInt(0)
+	for _, res := range results {
+		q1.Add(q1, res.PShare)
+		q2.Add(q2, res.QShare)
+	}
+
+	if new(big.Int).Mul(q1, q2).Cmp(results[0].Pk.N) != 0 {
+		t.Fatalf("Shares do not multiply to N\n")
+	}
+
+	if !q1.ProbablyPrime(20) || !q2.ProbablyPrime(20) {
+		t.Fatalf("N is not a product of two primes\n")
+	}
+
+	// Q must have order q1
+	pk := results[0].Pk
+	test := pk.Q.NewFieldElement()
+	test.PowBig(pk.Q, q1)
+	if !test.Equals(pk.G1.NewFieldElement()) {
+		t.Errorf("Q does not have order q1\n")
+	}
+}
+
+func TestDKGThresholdDecrypt(t *testing.T) {
+
+	results, err := RunDKG(newDKGConfig(4, 3))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	pk, tp := combinerKey(t, results)
+
+	if err := pk.SetupThresholdDecryption(tp); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	c1 := pk.Encrypt(big.NewInt(6))
+	c2 := pk.Encrypt(big.NewInt(-7))
+	cts := []*Ciphertext{pk.Add(c1, c2), pk.Mult(c1, c2)}
+	expected := []int64{-1, -42}
+
+	for i, ct := range cts {
+		partials := make([]*PartialDecryption, 0)
+		for _, res := range results[:3] {
+			partials = append(partials, pk.NewPartialDecryption(tp, res.KeyShare, ct))
+		}
+
+		// level1 partial decryptions combine to e(P, P)^(q1*m) in GT
+		if !ct.L2 {
+			combined, err := pk.CombinePartialDecryptions(tp, ct, partials)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			// the result is negative: e(P, P)^(q1*m) = (e(P, P)^q1)^-|m|
+			gt := pk.DecryptionVK.NewFieldElement()
+			gt.PowBig(pk.DecryptionVK, big.NewInt(-expected[i]))
+			gt.Invert(gt)
+			if !bytes.Equal(combined.Bytes(), gt.Bytes()) {
+				t.Errorf("Level1 partial decryptions were not combined in GT\n")
+			}
+		}
+
+		actual, err := pk.ThresholdDecrypt(tp, ct, partials)
+		if err != nil {
+			t.Fatalf("Error when decrypting %v\n", err.Error())
+		}
+
+		if actual.Int64() != expected[i] {
+			t.Errorf("Expected %d got %v\n", expected[i], actual)
+		}
+	}
+}
+
+func TestDKGInvalidConfig(t *testing.T) {
+
+	if _, err := RunDKG(newDKGConfig(2, 1)); err == nil {
+		t.Errorf("Expected error for too few parties\n")
+	}
+
+	if _, err := RunDKG(newDKGConfig(3, 4)); err == nil {
+		t.Errorf("Expected error for threshold larger than number of parties\n")
+	}
+}
@@ -0,0 +1,514 @@
+package bgn
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/Nik-U/pbc"
+)
+
+// Every proof can be marshalled to bytes (gob) or to JSON. Since group
+// elements are only meaningful with respect to a pairing, decoding
+// requires the public key, as for NewCiphertextFromBytes
+
+var errMalformedProof = errors.New("malformed proof")
+
+type proofOfPlaintextKnowledgeWrapper struct {
+	Ct    []byte
+	Nonce []byte
+	V     *big.Int
+	Z     *big.Int
+}
+
+type correctDecryptionProofWrapper struct {
+	Value *big.Int
+	A     []byte
+	B     []byte
+	Z     *big.Int
+	L2    bool
+}
+
+type multiplicationProofWrapper struct {
+	A []byte
+	Z *big.Int
+}
+
+type rerandomizationProofWrapper struct {
+	A  []byte
+	Z  *big.Int
+	L2 bool
+}
+
+type bitProofWrapper struct {
+	A0 []byte
+	A1 []byte
+	C0 *big.Int
+	C1 *big.Int
+	Z0 *big.Int
+	Z1 *big.Int
+}
+
+// decodeFunc unmarshals data into a proof wrapper
+type decodeFunc func(data []byte, w interface{}) error
+
+func gobEncode(w interface{}) ([]byte, error) {
+
+	// use default gob encoder
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(w); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func gobDecode(data []byte, w interface{}) error {
+
+	if len(data) == 0 {
+		return errors.New("no data provided")
+	}
+
+	reader := bytes.NewReader(data)
+	dec := gob.NewDecoder(reader)
+	return dec.Decode(w)
+}
+
+func jsonDecode(data []byte, w interface{}) error {
+
+	if len(data) == 0 {
+		return errors.New("no data provided")
+	}
+
+	return json.Unmarshal(data, w)
+}
+
+// elementFromBytes decodes an element of G1 (or GT if l2 is set)
+func (pk *PublicKey) elementFromBytes(data []byte, l2 bool) (*pbc.Element, error) {
+
+	if len(data) == 0 {
+		return nil, errMalformedProof
+	}
+
+	elem, err := pk.decodeElement(data, l2)
+	if err != nil {
+		return nil, errMalformedProof
+	}
+
+	return elem, nil
+}
+
+func (proof *ProofOfPlaintextKnowledge) wrapper() (*proofOfPlaintextKnowledgeWrapper, error) {
+
+	ct, err := proof.Ct.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := proof.Nonce.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &proofOfPlaintextKnowledgeWrapper{ct, nonce, proof.V, proof.Z}, nil
+}
+
+// Bytes returns the marshalled bytes of the proof
+func (proof *ProofOfPlaintextKnowledge) Bytes() ([]byte, error) {
+
+	w, err := proof.wrapper()
+	if err != nil {
+		return nil, err
+	}
+
+	return gobEncode(w)
+}
+
+// MarshalJSON returns the JSON encoding of the proof
+func (proof *ProofOfPlaintextKnowledge) MarshalJSON() ([]byte, error) {
+
+	w, err := proof.wrapper()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(w)
+}
+
+// NewEmptyProofOfPlaintextKnowledge returns an empty proof bound to the public key
+// which can be used as the target of json.Unmarshal
+func (pk *PublicKey) NewEmptyProofOfPlaintextKnowledge() *ProofOfPlaintextKnowledge {
+	return &ProofOfPlaintextKnowledge{pk: pk}
+}
+
+// UnmarshalJSON decodes the JSON encoding of the proof. The proof must be
+// bound to a public key (see NewEmptyProofOfPlaintextKnowledge) to ensure the correct pairing is used
+func (proof *ProofOfPlaintextKnowledge) UnmarshalJSON(data []byte) error {
+
+	if proof.pk == nil {
+		return errors.New("proof of plaintext knowledge is not bound to a public key")
+	}
+
+	res, err := proof.pk.decodeProofOfPlaintextKnowledge(data, jsonDecode)
+	if err != nil {
+		return err
+	}
+
+	*proof = *res
+	return nil
+}
+
+// NewProofOfPlaintextKnowledgeFromBytes generates a proof from a marshalled proof.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewProofOfPlaintextKnowledgeFromBytes(data []byte) (*ProofOfPlaintextKnowledge, error) {
+	return pk.decodeProofOfPlaintextKnowledge(data, gobDecode)
+}
+
+// NewProofOfPlaintextKnowledgeFromJSON generates a proof from its JSON encoding.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewProofOfPlaintextKnowledgeFromJSON(data []byte) (*ProofOfPlaintextKnowledge, error) {
+	return pk.decodeProofOfPlaintextKnowledge(data, jsonDecode)
+}
+
+func (pk *PublicKey) decodeProofOfPlaintextKnowledge(data []byte, decode decodeFunc) (*ProofOfPlaintextKnowledge, error) {
+
+	w := proofOfPlaintextKnowledgeWrapper{}
+	if err := decode(data, &w); err != nil {
+		return nil, err
+	}
+
+	if w.V == nil || w.Z == nil {
+		return nil, errMalformedProof
+	}
+
+	ct, err := pk.NewCiphertextFromBytes(w.Ct)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := pk.NewCiphertextFromBytes(w.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProofOfPlaintextKnowledge{ct, nonce, w.V, w.Z, pk}, nil
+}
+
+// Bytes returns the marshalled bytes of the proof
+func (proof *DecryptionProof) Bytes() ([]byte, error) {
+	return gobEncode(proof)
+}
+
+// NewDecryptionProofFromBytes generates a proof from a marshalled proof
+func (pk *PublicKey) NewDecryptionProofFromBytes(data []byte) (*DecryptionProof, error) {
+	return pk.decodeDecryptionProof(data, gobDecode)
+}
+
+// NewDecryptionProofFromJSON generates a proof from its JSON encoding
+func (pk *PublicKey) NewDecryptionProofFromJSON(data []byte) (*DecryptionProof, error) {
+	return pk.decodeDecryptionProof(data, jsonDecode)
+}
+
+func (pk *PublicKey) decodeDecryptionProof(data []byte, decode decodeFunc) (*DecryptionProof, error) {
+
+	proof := &DecryptionProof{}
+	if err := decode(data, proof); err != nil {
+		return nil, err
+	}
+
+	if proof.Value == nil || proof.Randomness == nil {
+		return nil, errMalformedProof
+	}
+
+	return proof, nil
+}
+
+func (proof *CorrectDecryptionProof) wrapper() *correctDecryptionProofWrapper {
+	return &correctDecryptionProofWrapper{proof.Value, proof.A.Bytes(), proof.B.Bytes(), proof.Z, proof.L2}
+}
+
+// Bytes returns the marshalled bytes of the proof
+func (proof *CorrectDecryptionProof) Bytes() ([]byte, error) {
+	return gobEncode(proof.wrapper())
+}
+
+// MarshalJSON returns the JSON encoding of the proof
+func (proof *CorrectDecryptionProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(proof.wrapper())
+}
+
+// NewEmptyCorrectDecryptionProof returns an empty proof bound to the public key
+// which can be used as the target of json.Unmarshal
+func (pk *PublicKey) NewEmptyCorrectDecryptionProof() *CorrectDecryptionProof {
+	return &CorrectDecryptionProof{pk: pk}
+}
+
+// UnmarshalJSON decodes the JSON encoding of the proof. The proof must be
+// bound to a public key (see NewEmptyCorrectDecryptionProof) to ensure the correct pairing is used
+func (proof *CorrectDecryptionProof) UnmarshalJSON(data []byte) error {
+
+	if proof.pk == nil {
+		return errors.New("correct decryption proof is not bound to a public key")
+	}
+
+	res, err := proof.pk.decodeCorrectDecryptionProof(data, jsonDecode)
+	if err != nil {
+		return err
+	}
+
+	*proof = *res
+	return nil
+}
+
+// NewCorrectDecryptionProofFromBytes generates a proof from a marshalled proof.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewCorrectDecryptionProofFromBytes(data []byte) (*CorrectDecryptionProof, error) {
+	return pk.decodeCorrectDecryptionProof(data, gobDecode)
+}
+
+// NewCorrectDecryptionProofFromJSON generates a proof from its JSON encoding.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewCorrectDecryptionProofFromJSON(data []byte) (*CorrectDecryptionProof, error) {
+	return pk.decodeCorrectDecryptionProof(data, jsonDecode)
+}
+
+func (pk *PublicKey) decodeCorrectDecryptionProof(data []byte, decode decodeFunc) (*CorrectDecryptionProof, error) {
+
+	w := correctDecryptionProofWrapper{}
+	if err := decode(data, &w); err != nil {
+		return nil, err
+	}
+
+	if w.Value == nil || w.Z == nil {
+		return nil, errMalformedProof
+	}
+
+	A, err := pk.elementFromBytes(w.A, true)
+	if err != nil {
+		return nil, err
+	}
+
+	B, err := pk.elementFromBytes(w.B, w.L2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CorrectDecryptionProof{w.Value, A, B, w.Z, w.L2, pk}, nil
+}
+
+func (proof *MultiplicationProof) wrapper() *multiplicationProofWrapper {
+	return &multiplicationProofWrapper{proof.A.Bytes(), proof.Z}
+}
+
+// Bytes returns the marshalled bytes of the proof
+func (proof *MultiplicationProof) Bytes() ([]byte, error) {
+	return gobEncode(proof.wrapper())
+}
+
+// MarshalJSON returns the JSON encoding of the proof
+func (proof *MultiplicationProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(proof.wrapper())
+}
+
+// NewEmptyMultiplicationProof returns an empty proof bound to the public key
+// which can be used as the target of json.Unmarshal
+func (pk *PublicKey) NewEmptyMultiplicationProof() *MultiplicationProof {
+	return &MultiplicationProof{pk: pk}
+}
+
+// UnmarshalJSON decodes the JSON encoding of the proof. The proof must be
+// bound to a public key (see NewEmptyMultiplicationProof) to ensure the correct pairing is used
+func (proof *MultiplicationProof) UnmarshalJSON(data []byte) error {
+
+	if proof.pk == nil {
+		return errors.New("multiplication proof is not bound to a public key")
+	}
+
+	res, err := proof.pk.decodeMultiplicationProof(data, jsonDecode)
+	if err != nil {
+		return err
+	}
+
+	*proof = *res
+	return nil
+}
+
+// NewMultiplicationProofFromBytes generates a proof from a marshalled proof.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewMultiplicationProofFromBytes(data []byte) (*MultiplicationProof, error) {
+	return pk.decodeMultiplicationProof(data, gobDecode)
+}
+
+// NewMultiplicationProofFromJSON generates a proof from its JSON encoding.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewMultiplicationProofFromJSON(data []byte) (*MultiplicationProof, error) {
+	return pk.decodeMultiplicationProof(data, jsonDecode)
+}
+
+func (pk *PublicKey) decodeMultiplicationProof(data []byte, decode decodeFunc) (*MultiplicationProof, error) {
+
+	w := multiplicationProofWrapper{}
+	if err := decode(data, &w); err != nil {
+		return nil, err
+	}
+
+	if w.Z == nil {
+		return nil, errMalformedProof
+	}
+
+	A, err := pk.elementFromBytes(w.A, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MultiplicationProof{A, w.Z, pk}, nil
+}
+
+func (proof *RerandomizationProof) wrapper() *rerandomizationProofWrapper {
+	return &rerandomizationProofWrapper{proof.A.Bytes(), proof.Z, proof.L2}
+}
+
+// Bytes returns the marshalled bytes of the proof
+func (proof *RerandomizationProof) Bytes() ([]byte, error) {
+	return gobEncode(proof.wrapper())
+}
+
+// MarshalJSON returns the JSON encoding of the proof
+func (proof *RerandomizationProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(proof.wrapper())
+}
+
+// NewEmptyRerandomizationProof returns an empty proof bound to the public key
+// which can be used as the target of json.Unmarshal
+func (pk *PublicKey) NewEmptyRerandomizationProof() *RerandomizationProof {
+	return &RerandomizationProof{pk: pk}
+}
+
+// UnmarshalJSON decodes the JSON encoding of the proof. The proof must be
+// bound to a public key (see NewEmptyRerandomizationProof) to ensure the correct pairing is used
+func (proof *RerandomizationProof) UnmarshalJSON(data []byte) error {
+
+	if proof.pk == nil {
+		return errors.New("rerandomization proof is not bound to a public key")
+	}
+
+	res, err := proof.pk.decodeRerandomizationProof(data, jsonDecode)
+	if err != nil {
+		return err
+	}
+
+	*proof = *res
+	return nil
+}
+
+// NewRerandomizationProofFromBytes generates a proof from a marshalled proof.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewRerandomizationProofFromBytes(data []byte) (*RerandomizationProof, error) {
+	return pk.decodeRerandomizationProof(data, gobDecode)
+}
+
+// NewRerandomizationProofFromJSON generates a proof from its JSON encoding.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewRerandomizationProofFromJSON(data []byte) (*RerandomizationProof, error) {
+	return pk.decodeRerandomizationProof(data, jsonDecode)
+}
+
+func (pk *PublicKey) decodeRerandomizationProof(data []byte, decode decodeFunc) (*RerandomizationProof, error) {
+
+	w := rerandomizationProofWrapper{}
+	if err := decode(data, &w); err != nil {
+		return nil, err
+	}
+
+	if w.Z == nil {
+		return nil, errMalformedProof
+	}
+
+	A, err := pk.elementFromBytes(w.A, w.L2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RerandomizationProof{A, w.Z, w.L2, pk}, nil
+}
+
+func (proof *BitProof) wrapper() *bitProofWrapper {
+	return &bitProofWrapper{proof.A0.Bytes(), proof.A1.Bytes(), proof.C0, proof.C1, proof.Z0, proof.Z1}
+}
+
+// Bytes returns the marshalled bytes of the proof
+func (proof *BitProof) Bytes() ([]byte, error) {
+	return gobEncode(proof.wrapper())
+}
+
+// MarshalJSON returns the JSON encoding of the proof
+func (proof *BitProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(proof.wrapper())
+}
+
+// NewEmptyBitProof returns an empty proof bound to the public key
+// which can be used as the target of json.Unmarshal
+func (pk *PublicKey) NewEmptyBitProof() *BitProof {
+	return &BitProof{pk: pk}
+}
+
+// UnmarshalJSON decodes the JSON encoding of the proof. The proof must be
+// bound to a public key (see NewEmptyBitProof) to ensure the correct pairing is used
+func (proof *BitProof) UnmarshalJSON(data []byte) error {
+
+	if proof.pk == nil {
+		return errors.New("bit proof is not bound to a public key")
+	}
+
+	res, err := proof.pk.decodeBitProof(data, jsonDecode)
+	if err != nil {
+		return err
+	}
+
+	*proof = *res
+	return nil
+}
+
+// NewBitProofFromBytes generates a proof from a marshalled proof.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewBitProofFromBytes(data []byte) (*BitProof, error) {
+	return pk.decodeBitProof(data, gobDecode)
+}
+
+// NewBitProofFromJSON generates a proof from its JSON encoding.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewBitProofFromJSON(data []byte) (*BitProof, error) {
+	return pk.decodeBitProof(data, jsonDecode)
+}
+
+func (pk *PublicKey) decodeBitProof(data []byte, decode decodeFunc) (*BitProof, error) {
+
+	w := bitProofWrapper{}
+	if err := decode(data, &w); err != nil {
+		return nil, err
+	}
+
+	return pk.bitProofFromWrapper(&w)
+}
+
+func (pk *PublicKey) bitProofFromWrapper(w *bitProofWrapper) (*BitProof, error) {
+
+	if w.C0 == nil || w.C1 == nil || w.Z0 == nil || w.Z1 == nil {
+		return nil, errMalformedProof
+	}
+
+	a0, err := pk.elementFromBytes(w.A0, false)
+	if err != nil {
+		return nil, err
+	}
+
+	a1, err := pk.elementFromBytes(w.A1, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BitProof{a0, a1, w.C0, w.C1, w.Z0, w.Z1, pk}, nil
+}
@@ -0,0 +1,326 @@
+package bgn
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestProofOfPlaintextKnowledgeToFromBytes(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	proof := pk.NewProofOfPlaintextKnowledge(big.NewInt(42), newCryptoRandom(pk.N), "session")
+
+	bytes, err := proof.Bytes()
+	if err != nil {
+		t.Fatalf("Error when encoding proof to bytes %v\n", err.Error())
+	}
+
+	recovered, err := pk.NewProofOfPlaintextKnowledgeFromBytes(bytes)
+	if err != nil {
+		t.Fatalf("Error when recovering proof from bytes %v\n", err.Error())
+	}
+
+	if !pk.CheckProofOfPlaintextKnoewledge(recovered.Ct, recovered, "session") {
+		t.Errorf("Recovered proof rejected")
+	}
+
+	data, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatalf("Error when encoding proof to JSON %v\n", err.Error())
+	}
+
+	recovered, err = pk.NewProofOfPlaintextKnowledgeFromJSON(data)
+	if err != nil {
+		t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+	}
+
+	if !pk.CheckProofOfPlaintextKnoewledge(recovered.Ct, recovered, "session") {
+		t.Errorf("Recovered proof rejected")
+	}
+
+	// the proof can be embedded in other JSON documents
+	data, _ = json.Marshal(struct{ Proof *ProofOfPlaintextKnowledge }{proof})
+
+	msg := struct{ Proof *ProofOfPlaintextKnowledge }{pk.NewEmptyProofOfPlaintextKnowledge()}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+	}
+
+	if !pk.CheckProofOfPlaintextKnoewledge(msg.Proof.Ct, msg.Proof, "session") {
+		t.Errorf("Recovered embedded proof rejected")
+	}
+
+	if err := json.Unmarshal(data, &struct{ Proof *ProofOfPlaintextKnowledge }{}); err == nil {
+		t.Errorf("Proof decoded without a public key")
+	}
+}
+
+func TestDecryptionProofToFromBytes(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	r := newCryptoRandom(pk.N)
+	v := big.NewInt(7)
+	ct := pk.EncryptWithRandomness(v, r)
+	proof := NewDecryptionProof(v, r)
+
+	bytes, err := proof.Bytes()
+	if err != nil {
+		t.Fatalf("Error when encoding proof to bytes %v\n", err.Error())
+	}
+
+	recovered, err := pk.NewDecryptionProofFromBytes(bytes)
+	if err != nil {
+		t.Fatalf("Error when recovering proof from bytes %v\n", err.Error())
+	}
+
+	if !pk.CheckDecryptionProof(ct, recovered) {
+		t.Errorf("Recovered proof rejected")
+	}
+
+	data, _ := json.Marshal(proof)
+	recovered, err = pk.NewDecryptionProofFromJSON(data)
+	if err != nil {
+		t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+	}
+
+	if !pk.CheckDecryptionProof(ct, recovered) {
+		t.Errorf("Recovered proof rejected")
+	}
+}
+
+func TestCorrectDecryptionProofToFromBytes(t *testing.T) {
+
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	c1 := pk.Encrypt(big.NewInt(9))
+	c2 := pk.Mult(c1, pk.Encrypt(big.NewInt(-4)))
+
+	for _, ct := range []*Ciphertext{c1, c2} {
+		proof, _ := sk.NewCorrectDecryptionProof(pk, ct)
+
+		bytes, err := proof.Bytes()
+		if err != nil {
+			t.Fatalf("Error when encoding proof to bytes %v\n", err.Error())
+		}
+
+		recovered, err := pk.NewCorrectDecryptionProofFromBytes(bytes)
+		if err != nil {
+			t.Fatalf("Error when recovering proof from bytes %v\n", err.Error())
+		}
+
+		if !pk.VerifyCorrectDecryptionProof(ct, recovered) {
+			t.Errorf("[L2=%v] Recovered proof rejected", ct.L2)
+		}
+
+		data, _ := json.Marshal(proof)
+		recovered, err = pk.NewCorrectDecryptionProofFromJSON(data)
+		if err != nil {
+			t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+		}
+
+		if !pk.VerifyCorrectDecryptionProof(ct, recovered) {
+			t.Errorf("[L2=%v] Recovered proof rejected", ct.L2)
+		}
+
+		// the proof can be embedded in other JSON documents
+		data, _ = json.Marshal(struct{ Proof *CorrectDecryptionProof }{proof})
+
+		msg := struct{ Proof *CorrectDecryptionProof }{pk.NewEmptyCorrectDecryptionProof()}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+		}
+
+		if !pk.VerifyCorrectDecryptionProof(ct, msg.Proof) {
+			t.Errorf("Recovered embedded proof rejected")
+		}
+
+		if err := json.Unmarshal(data, &struct{ Proof *CorrectDecryptionProof }{}); err == nil {
+			t.Errorf("Proof decoded without a public key")
+		}
+	}
+}
+
+func TestMultiplicationProofToFromBytes(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	c1 := pk.Encrypt(big.NewInt(3))
+	c2 := pk.Encrypt(big.NewInt(5))
+	r := newCryptoRandom(pk.N)
+	res := pk.MultWithRandomness(c1, c2, r)
+	proof := pk.NewMultiplicationProof(c1, c2, r)
+
+	bytes, err := proof.Bytes()
+	if err != nil {
+		t.Fatalf("Error when encoding proof to bytes %v\n", err.Error())
+	}
+
+	recovered, err := pk.NewMultiplicationProofFromBytes(bytes)
+	if err != nil {
+		t.Fatalf("Error when recovering proof from bytes %v\n", err.Error())
+	}
+
+	if !pk.VerifyMultiplicationProof(c1, c2, res, recovered) {
+		t.Errorf("Recovered proof rejected")
+	}
+
+	data, _ := json.Marshal(proof)
+	recovered, err = pk.NewMultiplicationProofFromJSON(data)
+	if err != nil {
+		t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+	}
+
+	if !pk.VerifyMultiplicationProof(c1, c2, res, recovered) {
+		t.Errorf("Recovered proof rejected")
+	}
+
+	// the proof can be embedded in other JSON documents
+	data, _ = json.Marshal(struct{ Proof *MultiplicationProof }{proof})
+
+	msg := struct{ Proof *MultiplicationProof }{pk.NewEmptyMultiplicationProof()}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+	}
+
+	if !pk.VerifyMultiplicationProof(c1, c2, res, msg.Proof) {
+		t.Errorf("Recovered embedded proof rejected")
+	}
+
+	if err := json.Unmarshal(data, &struct{ Proof *MultiplicationProof }{}); err == nil {
+		t.Errorf("Proof decoded without a public key")
+	}
+}
+
+func TestRerandomizationProofToFromBytes(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	c1 := pk.Encrypt(big.NewInt(3))
+	c2 := pk.Mult(c1, pk.Encrypt(big.NewInt(2)))
+
+	for _, ct := range []*Ciphertext{c1, c2} {
+		r := newCryptoRandom(pk.N)
+		res := pk.RerandomizeWithRandomness(ct, r)
+		proof := pk.NewRerandomizationProof(ct, r)
+
+		bytes, err := proof.Bytes()
+		if err != nil {
+			t.Fatalf("Error when encoding proof to bytes %v\n", err.Error())
+		}
+
+		recovered, err := pk.NewRerandomizationProofFromBytes(bytes)
+		if err != nil {
+			t.Fatalf("Error when recovering proof from bytes %v\n", err.Error())
+		}
+
+		if !pk.VerifyRerandomizationProof(ct, res, recovered) {
+			t.Errorf("[L2=%v] Recovered proof rejected", ct.L2)
+		}
+
+		data, _ := json.Marshal(proof)
+		recovered, err = pk.NewRerandomizationProofFromJSON(data)
+		if err != nil {
+			t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+		}
+
+		if !pk.VerifyRerandomizationProof(ct, res, recovered) {
+			t.Errorf("[L2=%v] Recovered proof rejected", ct.L2)
+		}
+
+		// the proof can be embedded in other JSON documents
+		data, _ = json.Marshal(struct{ Proof *RerandomizationProof }{proof})
+
+		msg := struct{ Proof *RerandomizationProof }{pk.NewEmptyRerandomizationProof()}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+		}
+
+		if !pk.VerifyRerandomizationProof(ct, res, msg.Proof) {
+			t.Errorf("Recovered embedded proof rejected")
+		}
+
+		if err := json.Unmarshal(data, &struct{ Proof *RerandomizationProof }{}); err == nil {
+			t.Errorf("Proof decoded without a public key")
+		}
+	}
+}
+
+func TestBitProofToFromBytes(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	r := newCryptoRandom(pk.N)
+	ct := pk.EncryptWithRandomness(big.NewInt(1), r)
+	proof := pk.NewBitProof(big.NewInt(1), r)
+
+	bytes, err := proof.Bytes()
+	if err != nil {
+		t.Fatalf("Error when encoding proof to bytes %v\n", err.Error())
+	}
+
+	recovered, err := pk.NewBitProofFromBytes(bytes)
+	if err != nil {
+		t.Fatalf("Error when recovering proof from bytes %v\n", err.Error())
+	}
+
+	if !pk.VerifyBitProof(ct, recovered) {
+		t.Errorf("Recovered proof rejected")
+	}
+
+	data, _ := json.Marshal(proof)
+	recovered, err = pk.NewBitProofFromJSON(data)
+	if err != nil {
+		t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+	}
+
+	if !pk.VerifyBitProof(ct, recovered) {
+		t.Errorf("Recovered proof rejected")
+	}
+
+	// the proof can be embedded in other JSON documents
+	data, _ = json.Marshal(struct{ Proof *BitProof }{proof})
+
+	msg := struct{ Proof *BitProof }{pk.NewEmptyBitProof()}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+	}
+
+	if !pk.VerifyBitProof(ct, msg.Proof) {
+		t.Errorf("Recovered embedded proof rejected")
+	}
+
+	if err := json.Unmarshal(data, &struct{ Proof *BitProof }{}); err == nil {
+		t.Errorf("Proof decoded without a public key")
+	}
+}
+
+func TestProofFromBytesMalformed(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	if _, err := pk.NewBitProofFromBytes(nil); err == nil {
+		t.Errorf("Empty data accepted")
+	}
+
+	if _, err := pk.NewMultiplicationProofFromJSON([]byte(`{"Z": 5}`)); err == nil {
+		t.Errorf("Proof without commitment accepted")
+	}
+
+	if _, err := pk.NewDecryptionProofFromJSON([]byte(`{"Value": 5}`)); err == nil {
+		t.Errorf("Proof without randomness accepted")
+	}
+
+	// commitment with a small order component
+	r := newCryptoRandom(pk.N)
+	proof := pk.NewBitProof(big.NewInt(1), r)
+	proof.A0.Mul(proof.A0, outOfSubgroupElement(t, pk))
+
+	data, _ := proof.Bytes()
+	if _, err := pk.NewBitProofFromBytes(data); err == nil {
+		t.Errorf("Commitment outside the subgroup of order N accepted")
+	}
+}
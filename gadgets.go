@@ -1,16 +1,23 @@
 package bgn
 
 import (
-	"crypto/sha256"
+	"crypto/rand"
+	"errors"
 	"math/big"
+	"sort"
+
+	"github.com/Nik-U/pbc"
 )
 
-// ProofOfPlaintextKnowledge is a proof that
-// the value encrypted by the ciphertext is known
+// ProofOfPlaintextKnowledge is a proof that the value v and
+// randomness z encrypted by the ciphertext P^v * Q^z are known
 type ProofOfPlaintextKnowledge struct {
-	Ct    *Ciphertext
-	Nonce *Ciphertext
-	DL    *big.Int
+	Ct    *Ciphertext // P^v * Q^z
+	Nonce *Ciphertext // P^a * Q^b
+	V     *big.Int    // a + c*v
+	Z     *big.Int    // b + c*z
+
+	pk *PublicKey // key used to decode the proof in UnmarshalJSON
 }
 
 // DecryptionProof is a proof that a ciphertext
@@ -20,6 +27,55 @@ type DecryptionProof struct {
 	Randomness *big.Int
 }
 
+// CorrectDecryptionProof is a proof, generated by the secret key
+// holder, that ct decrypts to Value. It is a Chaum-Pedersen proof that
+// the exponent q1 committed to in pk.DecryptionVK = e(P, P)^q1
+// also annihilates ct / g^Value
+type CorrectDecryptionProof struct {
+	Value *big.Int
+	A     *pbc.Element // e(P, P)^k
+	B     *pbc.Element // (ct / g^Value)^k
+	Z     *big.Int     // k + c*q1
+	L2    bool         // indicates whether the proven ciphertext is at level2
+
+	pk *PublicKey // key used to decode the proof in UnmarshalJSON
+}
+
+// MultiplicationProof is a proof that a level2 ciphertext is the
+// product of two level1 ciphertexts re-randomized with e(Q, Q)^r
+// (a Schnorr proof of knowledge of r such that res / e(ct1, ct2) = e(Q, Q)^r)
+type MultiplicationProof struct {
+	A *pbc.Element // e(Q, Q)^k
+	Z *big.Int     // k + c*r
+
+	pk *PublicKey // key used to decode the proof in UnmarshalJSON
+}
+
+// RerandomizationProof is a proof that a ciphertext is a
+// re-randomization of another (a Schnorr proof of knowledge
+// of r such that res / ct = Q^r or e(Q, Q)^r)
+type RerandomizationProof struct {
+	A  *pbc.Element // Q^k or e(Q, Q)^k
+	Z  *big.Int     // k + c*r
+	L2 bool         // indicates whether the ciphertexts are at level2
+
+	pk *PublicKey // key used to decode the proof in UnmarshalJSON
+}
+
+// BitProof is a proof that a level1 ciphertext
+// encrypts either 0 or 1 (a disjunctive Schnorr proof
+// that either ct or ct/P is of the form Q^r)
+type BitProof struct {
+	A0 *pbc.Element // commitment for the branch v = 0
+	A1 *pbc.Element // commitment for the branch v = 1
+	C0 *big.Int     // challenge for the branch v = 0
+	C1 *big.Int     // challenge for the branch v = 1
+	Z0 *big.Int     // response for the branch v = 0
+	Z1 *big.Int     // response for the branch v = 1
+
+	pk *PublicKey // key used to decode the proof in UnmarshalJSON
+}
+
 // NewDecryptionProof constructs a new proof for value v and randomness r
 func NewDecryptionProof(v *big.Int, r *big.Int) *DecryptionProof {
 	return &DecryptionProof{
@@ -28,31 +84,416 @@ func NewDecryptionProof(v *big.Int, r *big.Int) *DecryptionProof {
 }
 
 // NewProofOfPlaintextKnowledge generates a proof of plaintext knowledge for a ciphertext encrypting
-// the value v with randomness z
-func (pk *PublicKey) NewProofOfPlaintextKnowledge(sk *SecretKey, v *big.Int, z *big.Int) *ProofOfPlaintextKnowledge {
-	nonce1 := newCryptoRandom(pk.N)
-	ct := pk.EncryptWithRandomness(v, z)                     // g^v * h^z = g^(v + Rzq)
-	nonce := pk.EncryptWithRandomness(nonce1, big.NewInt(0)) // g^r * h^0 = g^(r)
+// the value v with randomness z. Only the public key is needed. The context (e.g. a session identifier)
+// is bound to the proof, which then only verifies under the same context
+func (pk *PublicKey) NewProofOfPlaintextKnowledge(v *big.Int, z *big.Int, context string) *ProofOfPlaintextKnowledge {
+
+	a := newCryptoRandom(pk.N)
+	b := newCryptoRandom(pk.N)
+
+	ct := pk.EncryptWithRandomness(v, z)    // P^v * Q^z
+	nonce := pk.EncryptWithRandomness(a, b) // P^a * Q^b
+
+	c := pk.plaintextKnowledgeChallenge(ct, nonce, context)
+
+	V := new(big.Int).Mul(c, v)
+	V.Add(V, a)
+	V.Mod(V, pk.N) // a + cv
+
+	Z := new(big.Int).Mul(c, z)
+	Z.Add(Z, b)
+	Z.Mod(Z, pk.N) // b + cz
+
+	return &ProofOfPlaintextKnowledge{ct, nonce, V, Z, pk}
+}
+
+// NewCorrectDecryptionProof decrypts ct and proves that the
+// decryption is correct without revealing the secret key
+func (sk *SecretKey) NewCorrectDecryptionProof(pk *PublicKey, ct *Ciphertext) (*CorrectDecryptionProof, error) {
+
+	v, err := sk.Decrypt(ct, pk)
+	if err != nil {
+		return nil, err
+	}
+
+	x := pk.correctDecryptionStatement(ct, v)
+	base := pk.Pairing.NewGT().Pair(pk.P, pk.P)
+
+	k := newCryptoRandom(pk.N)
+
+	A := base.NewFieldElement()
+	A.PowBig(base, k)
+
+	B := x.NewFieldElement()
+	B.PowBig(x, k)
+
+	c := pk.correctDecryptionChallenge(ct, v, A, B)
+
+	Z := new(big.Int).Mul(c, sk.Key)
+	Z.Add(Z, k)
+	Z.Mod(Z, pk.N)
+
+	return &CorrectDecryptionProof{v, A, B, Z, ct.L2, pk}, nil
+}
+
+// VerifyCorrectDecryptionProof outputs true if proof shows that ct
+// (at either level) decrypts to proof.Value under the public key
+func (pk *PublicKey) VerifyCorrectDecryptionProof(ct *Ciphertext, proof *CorrectDecryptionProof) bool {
+
+	if pk.DecryptionVK == nil || proof == nil || proof.Value == nil ||
+		proof.A == nil || proof.B == nil || proof.Z == nil || proof.L2 != ct.L2 {
+		return false
+	}
+
+	x := pk.correctDecryptionStatement(ct, proof.Value)
+	base := pk.Pairing.NewGT().Pair(pk.P, pk.P)
+
+	c := pk.correctDecryptionChallenge(ct, proof.Value, proof.A, proof.B)
+
+	// e(P, P)^z = A * vk^c
+	lhs := base.NewFieldElement()
+	lhs.PowBig(base, proof.Z)
+	rhs := pk.DecryptionVK.NewFieldElement()
+	rhs.PowBig(pk.DecryptionVK, c)
+	rhs.Mul(rhs, proof.A)
+
+	if !lhs.Equals(rhs) {
+		return false
+	}
+
+	// (ct / g^v)^z = B since (ct / g^v)^q1 = 1
+	lhs = x.NewFieldElement()
+	lhs.PowBig(x, proof.Z)
+
+	return lhs.Equals(proof.B)
+}
+
+// correctDecryptionStatement computes ct / g^v where g is
+// P for level1 ciphertexts and e(P, P) for level2 ciphertexts
+func (pk *PublicKey) correctDecryptionStatement(ct *Ciphertext, v *big.Int) *pbc.Element {
 
-	proof := &ProofOfPlaintextKnowledge{
-		ct, nonce, nil,
+	g := pk.P
+	if ct.L2 {
+		g = pk.Pairing.NewGT().Pair(pk.P, pk.P)
 	}
 
-	nonce2 := hash(proof)
-	DL := big.NewInt(0)
-	DL.Add(DL, nonce1)                      // r
-	DL.Add(DL, new(big.Int).Mul(nonce2, v)) // r + cv
-	tmp := new(big.Int).Mul(sk.R, z)
-	tmp.Mul(tmp, nonce2)                         // Rzc
-	tmp.Mul(tmp, new(big.Int).Div(pk.N, sk.Key)) // Rzcq
-	DL.Add(DL, tmp)                              //  r + cv + Rzcq
-	DL.Mod(DL, pk.N)
+	gv := g.NewFieldElement()
+	gv.PowBig(g, new(big.Int).Mod(v, pk.N))
 
-	proof.DL = DL
+	x := ct.C.NewFieldElement()
+	x.Div(ct.C, gv)
+
+	return x
+}
+
+// NewMultiplicationProof generates a proof that MultWithRandomness(ct1, ct2, r)
+// is the product of ct1 and ct2
+func (pk *PublicKey) NewMultiplicationProof(ct1 *Ciphertext, ct2 *Ciphertext, r *big.Int) *MultiplicationProof {
+
+	res := pk.MultWithRandomness(ct1, ct2, r)
+	A, Z := pk.newSchnorr(pk.rerandomizationBase(true), r, func(A *pbc.Element) *big.Int {
+		return pk.multiplicationChallenge(ct1, ct2, res, A)
+	})
+
+	return &MultiplicationProof{A, Z, pk}
+}
+
+// VerifyMultiplicationProof outputs true if proof shows that the
+// level2 ciphertext res is a re-randomized product of ct1 and ct2
+func (pk *PublicKey) VerifyMultiplicationProof(ct1 *Ciphertext, ct2 *Ciphertext, res *Ciphertext, proof *MultiplicationProof) bool {
+
+	if ct1.L2 || ct2.L2 || !res.L2 || proof == nil || proof.A == nil || proof.Z == nil {
+		return false
+	}
+
+	pk.mu.Lock()
+	y := pk.Pairing.NewGT().Pair(ct1.C, ct2.C)
+	pk.mu.Unlock()
+	y.Div(res.C, y)
+
+	c := pk.multiplicationChallenge(ct1, ct2, res, proof.A)
+
+	return checkSchnorrBase(pk.rerandomizationBase(true), y, proof.A, c, proof.Z)
+}
+
+// NewRerandomizationProof generates a proof that RerandomizeWithRandomness(ct, r)
+// is a re-randomization of ct
+func (pk *PublicKey) NewRerandomizationProof(ct *Ciphertext, r *big.Int) *RerandomizationProof {
+
+	res := pk.RerandomizeWithRandomness(ct, r)
+	A, Z := pk.newSchnorr(pk.rerandomizationBase(ct.L2), r, func(A *pbc.Element) *big.Int {
+		return pk.rerandomizationChallenge(ct, res, A)
+	})
+
+	return &RerandomizationProof{A, Z, ct.L2, pk}
+}
+
+// VerifyRerandomizationProof outputs true if proof shows
+// that res is a re-randomization of ct
+func (pk *PublicKey) VerifyRerandomizationProof(ct *Ciphertext, res *Ciphertext, proof *RerandomizationProof) bool {
+
+	if ct.L2 != res.L2 || proof == nil || proof.A == nil || proof.Z == nil || proof.L2 != ct.L2 {
+		return false
+	}
+
+	y := res.C.NewFieldElement()
+	y.Div(res.C, ct.C)
+
+	c := pk.rerandomizationChallenge(ct, res, proof.A)
+
+	return checkSchnorrBase(pk.rerandomizationBase(ct.L2), y, proof.A, c, proof.Z)
+}
+
+// newSchnorr proves knowledge of x such that y = base^x,
+// where challenge computes the Fiat-Shamir challenge from the commitment
+func (pk *PublicKey) newSchnorr(base *pbc.Element, x *big.Int, challenge func(A *pbc.Element) *big.Int) (*pbc.Element, *big.Int) {
+
+	k := newCryptoRandom(pk.N)
+
+	A := base.NewFieldElement()
+	A.PowBig(base, k)
+
+	Z := new(big.Int).Mul(challenge(A), x)
+	Z.Add(Z, k)
+	Z.Mod(Z, pk.N)
+
+	return A, Z
+}
+
+// NewBitProof generates a proof that the ciphertext EncryptWithRandomness(v, r)
+// encrypts a bit. Only the public key and the encryption randomness are needed
+func (pk *PublicKey) NewBitProof(v *big.Int, r *big.Int) *BitProof {
+
+	if v.Cmp(big.NewInt(0)) != 0 && v.Cmp(big.NewInt(1)) != 0 {
+		panic("bit proofs can only be generated for 0 or 1")
+	}
+
+	ct := pk.EncryptWithRandomness(v, r)
+	y0, y1 := pk.bitProofStatements(ct)
+
+	// simulate the branch for the other bit
+	cf := newCryptoRandom(pk.N)
+	zf := newCryptoRandom(pk.N)
+	af := pk.Q.NewFieldElement()
+	af.PowBig(pk.Q, zf)
+	yc := pk.Q.NewFieldElement()
+	if v.Sign() == 0 {
+		yc.PowBig(y1, cf)
+	} else {
+		yc.PowBig(y0, cf)
+	}
+	af.Div(af, yc)
+
+	// commit for the true branch
+	k := newCryptoRandom(pk.N)
+	at := pk.Q.NewFieldElement()
+	at.PowBig(pk.Q, k)
+
+	proof := &BitProof{pk: pk}
+	if v.Sign() == 0 {
+		proof.A0, proof.A1 = at, af
+	} else {
+		proof.A0, proof.A1 = af, at
+	}
+
+	c := pk.bitChallenge(ct, proof.A0, proof.A1)
+	ct2 := new(big.Int).Sub(c, cf)
+	ct2.Mod(ct2, pk.N)
+
+	zt := new(big.Int).Mul(ct2, r)
+	zt.Add(zt, k)
+	zt.Mod(zt, pk.N)
+
+	if v.Sign() == 0 {
+		proof.C0, proof.Z0, proof.C1, proof.Z1 = ct2, zt, cf, zf
+	} else {
+		proof.C0, proof.Z0, proof.C1, proof.Z1 = cf, zf, ct2, zt
+	}
 
 	return proof
 }
 
+// VerifyBitProof outputs true if proof shows that the
+// level1 ciphertext ct encrypts either 0 or 1
+func (pk *PublicKey) VerifyBitProof(ct *Ciphertext, proof *BitProof) bool {
+
+	if !pk.checkBitProofChallenges(ct, proof) {
+		return false
+	}
+
+	y0, y1 := pk.bitProofStatements(ct)
+
+	return pk.checkSchnorr(y0, proof.A0, proof.C0, proof.Z0) &&
+		pk.checkSchnorr(y1, proof.A1, proof.C1, proof.Z1)
+}
+
+// checkBitProofChallenges outputs true if the proof is well formed
+// and the branch challenges sum to the Fiat-Shamir challenge
+func (pk *PublicKey) checkBitProofChallenges(ct *Ciphertext, proof *BitProof) bool {
+
+	if ct.L2 || proof == nil || proof.A0 == nil || proof.A1 == nil ||
+		proof.C0 == nil || proof.C1 == nil || proof.Z0 == nil || proof.Z1 == nil {
+		return false
+	}
+
+	c := pk.bitChallenge(ct, proof.A0, proof.A1)
+	sum := new(big.Int).Add(proof.C0, proof.C1)
+
+	return sum.Mod(sum, pk.N).Cmp(c) == 0
+}
+
+// bitProofStatements returns ct and ct/P, one of which is
+// of the form Q^r if ct encrypts a bit
+func (pk *PublicKey) bitProofStatements(ct *Ciphertext) (*pbc.Element, *pbc.Element) {
+	y1 := ct.C.NewFieldElement()
+	y1.Div(ct.C, pk.P)
+	return ct.C, y1
+}
+
+// checkSchnorr checks that Q^z = A * y^c
+func (pk *PublicKey) checkSchnorr(y *pbc.Element, A *pbc.Element, c *big.Int, z *big.Int) bool {
+	return checkSchnorrBase(pk.Q, y, A, c, z)
+}
+
+// checkSchnorrBase checks that base^z = A * y^c
+func checkSchnorrBase(base *pbc.Element, y *pbc.Element, A *pbc.Element, c *big.Int, z *big.Int) bool {
+	lhs := base.NewFieldElement()
+	lhs.PowBig(base, z)
+
+	rhs := y.NewFieldElement()
+	rhs.PowBig(y, c)
+	rhs.Mul(rhs, A)
+
+	return lhs.Equals(rhs)
+}
+
+// batchTerm is the factor base^exp of a batched equation
+type batchTerm struct {
+	base *pbc.Element
+	exp  *big.Int
+}
+
+// batchVerifier checks many equations of the form prod_i base_i^exp_i = 1
+// at once by raising each equation to a small random weight and
+// multiplying them together. Factors with negative exponents are moved to
+// the right hand side so that exponents are never reduced mod N, and both
+// sides are computed with a single multi-exponentiation. Exponents of bases
+// shared across equations (such as pk.Q) are summed so that each is raised only once.
+// All bases must lie in the subgroup of order N, as guaranteed for elements
+// decoded by this package; small order components could otherwise cancel out
+type batchVerifier struct {
+	pk     *PublicKey
+	shared []*pbc.Element
+	exps   map[*pbc.Element]*big.Int
+	lhs    []batchTerm
+	rhs    []batchTerm
+}
+
+func (pk *PublicKey) newBatchVerifier(shared ...*pbc.Element) *batchVerifier {
+	exps := make(map[*pbc.Element]*big.Int)
+	for _, base := range shared {
+		exps[base] = big.NewInt(0)
+	}
+
+	return &batchVerifier{pk: pk, shared: shared, exps: exps}
+}
+
+// addEquation adds the equation prod_i term_i.base^term_i.exp = 1
+func (bv *batchVerifier) addEquation(terms ...batchTerm) {
+
+	// 64 bit weights make a false batch pass with probability 2^-64
+	weight, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		panic(err)
+	}
+	weight.Add(weight, big.NewInt(1))
+
+	for _, term := range terms {
+		exp := new(big.Int).Mul(term.exp, weight)
+
+		if acc, ok := bv.exps[term.base]; ok {
+			acc.Add(acc, exp)
+			continue
+		}
+
+		bv.addTerm(term.base, exp)
+	}
+}
+
+// addTerm adds base^exp to the left hand side if exp is positive
+// and base^-exp to the right hand side otherwise
+func (bv *batchVerifier) addTerm(base *pbc.Element, exp *big.Int) {
+
+	switch exp.Sign() {
+	case 1:
+		bv.lhs = append(bv.lhs, batchTerm{base, exp})
+	case -1:
+		bv.rhs = append(bv.rhs, batchTerm{base, new(big.Int).Neg(exp)})
+	}
+}
+
+// verify outputs true if all the added equations (most likely) hold
+func (bv *batchVerifier) verify() bool {
+
+	// the shared bases are public key elements of order dividing N
+	for _, base := range bv.shared {
+		bv.addTerm(base, new(big.Int).Mod(bv.exps[base], bv.pk.N))
+	}
+
+	lhs := multiExp(bv.lhs)
+	rhs := multiExp(bv.rhs)
+
+	switch {
+	case lhs == nil && rhs == nil:
+		return true
+	case lhs == nil:
+		return rhs.Is1()
+	case rhs == nil:
+		return lhs.Is1()
+	}
+
+	return lhs.Equals(rhs)
+}
+
+// multiExp computes prod_i term_i.base^term_i.exp for non-negative exponents
+// using simultaneous square and multiply (nil if there are no terms)
+func multiExp(terms []batchTerm) *pbc.Element {
+
+	if len(terms) == 0 {
+		return nil
+	}
+
+	bits := 0
+	for _, term := range terms {
+		if term.exp.BitLen() > bits {
+			bits = term.exp.BitLen()
+		}
+	}
+
+	res := terms[0].base.NewFieldElement()
+	res.Set1()
+
+	for i := bits - 1; i >= 0; i-- {
+		res.Square(res)
+		for _, term := range terms {
+			if term.exp.Bit(i) == 1 {
+				res.Mul(res, term.base)
+			}
+		}
+	}
+
+	return res
+}
+
+// addSchnorr adds the equation Q^z = A * y^c to the batch
+func (bv *batchVerifier) addSchnorr(y *pbc.Element, A *pbc.Element, c *big.Int, z *big.Int) {
+	bv.addEquation(
+		batchTerm{bv.pk.Q, z},
+		batchTerm{A, big.NewInt(-1)},
+		batchTerm{y, new(big.Int).Neg(c)},
+	)
+}
+
 // CheckDecryptionProof outputs true if the proof is valid for the ciphertext ct
 func (pk *PublicKey) CheckDecryptionProof(ct *Ciphertext, proof *DecryptionProof) bool {
 
@@ -61,36 +502,149 @@ func (pk *PublicKey) CheckDecryptionProof(ct *Ciphertext, proof *DecryptionProof
 }
 
 // CheckProofOfPlaintextKnoewledge checks whether proof corresponds to a valid
-// proof of plaintext knowledge for the ciphertext ct
-func (pk *PublicKey) CheckProofOfPlaintextKnoewledge(ct *Ciphertext, proof *ProofOfPlaintextKnowledge) bool {
+// proof of plaintext knowledge for the ciphertext ct generated under the given context
+func (pk *PublicKey) CheckProofOfPlaintextKnoewledge(ct *Ciphertext, proof *ProofOfPlaintextKnowledge, context string) bool {
 
-	nonce2 := hash(proof)
+	if ct.L2 || proof == nil || proof.Nonce == nil || proof.V == nil || proof.Z == nil {
+		return false
+	}
+
+	c := pk.plaintextKnowledgeChallenge(ct, proof.Nonce, context)
 
 	res := ct.C.NewFieldElement()
-	res.PowBig(ct.C, nonce2)    // g^vc * h^zc = g^(vc + Rzcq)
-	res.Mul(res, proof.Nonce.C) // g^(r + vc + Rzcq)
+	res.PowBig(ct.C, c)         // P^cv * Q^cz
+	res.Mul(res, proof.Nonce.C) // P^(a + cv) * Q^(b + cz)
+
+	return pk.EncryptWithRandomness(proof.V, proof.Z).C.Equals(res)
+}
+
+// BatchCheckProofsOfPlaintextKnowledge checks the proofs of plaintext knowledge for
+// the ciphertexts cts with a single randomized multi-exponentiation. If the batch fails,
+// it is recursively split in halves to pinpoint the invalid proofs.
+// Outputs the indices of the invalid proofs (nil if all proofs are valid).
+// The ciphertexts and nonces must lie in the subgroup of order N, which holds
+// for ciphertexts and proofs produced or decoded by this package
+func (pk *PublicKey) BatchCheckProofsOfPlaintextKnowledge(cts []*Ciphertext, proofs []*ProofOfPlaintextKnowledge, context string) ([]int, error) {
+
+	if len(cts) != len(proofs) {
+		return nil, errors.New("number of ciphertexts and proofs differ")
+	}
+
+	var invalid []int
+	var indices []int
+	challenges := make([]*big.Int, len(proofs))
 
-	G := pk.P.NewFieldElement()
-	G.PowBig(pk.P, proof.DL) // g^(r + vc + Rzcq)
+	for i, proof := range proofs {
+		if cts[i].L2 || proof == nil || proof.Nonce == nil || proof.V == nil || proof.Z == nil {
+			invalid = append(invalid, i)
+			continue
+		}
 
-	return G.Equals(res)
+		challenges[i] = pk.plaintextKnowledgeChallenge(cts[i], proof.Nonce, context)
+		indices = append(indices, i)
+	}
+
+	if !pk.batchPlaintextKnowledge(cts, proofs, challenges, indices) {
+		invalid = append(invalid, pk.findInvalidPlaintextKnowledge(cts, proofs, challenges, indices)...)
+	}
+	sort.Ints(invalid)
+
+	return invalid, nil
 }
 
-// hash computes the sha2 hash of the provided proof
-func hash(proof *ProofOfPlaintextKnowledge) *big.Int {
+// findInvalidPlaintextKnowledge bisects a batch of proofs that failed verification.
+// A valid batch always passes, so the failure shows that some proof is invalid;
+// if both halves pass, one of them passed by chance and both are checked again
+func (pk *PublicKey) findInvalidPlaintextKnowledge(cts []*Ciphertext, proofs []*ProofOfPlaintextKnowledge, challenges []*big.Int, indices []int) []int {
 
-	bytes := make([]byte, 0)
+	if len(indices) == 1 {
+		return indices
+	}
 
-	bytes = append(bytes, proof.Ct.C.Bytes()...)
-	bytes = append(bytes, proof.Nonce.C.Bytes()...)
+	mid := len(indices) / 2
+	for {
+		var invalid []int
+		for _, half := range [][]int{indices[:mid], indices[mid:]} {
+			if !pk.batchPlaintextKnowledge(cts, proofs, challenges, half) {
+				invalid = append(invalid, pk.findInvalidPlaintextKnowledge(cts, proofs, challenges, half)...)
+			}
+		}
 
-	h := sha256.New()
-	_, err := h.Write([]byte(bytes))
-	if err != nil {
-		panic(err)
+		if len(invalid) > 0 {
+			return invalid
+		}
+	}
+}
+
+// batchPlaintextKnowledge batch verifies the proofs at the given indices
+func (pk *PublicKey) batchPlaintextKnowledge(cts []*Ciphertext, proofs []*ProofOfPlaintextKnowledge, challenges []*big.Int, indices []int) bool {
+
+	if len(indices) == 0 {
+		return true
 	}
 
-	hash := h.Sum(nil)
+	bv := pk.newBatchVerifier(pk.P, pk.Q)
+	for _, i := range indices {
+		// P^V * Q^Z = Nonce * ct^c
+		bv.addEquation(
+			batchTerm{pk.P, proofs[i].V},
+			batchTerm{pk.Q, proofs[i].Z},
+			batchTerm{proofs[i].Nonce.C, big.NewInt(-1)},
+			batchTerm{cts[i].C, new(big.Int).Neg(challenges[i])},
+		)
+	}
+
+	return bv.verify()
+}
+
+// plaintextKnowledgeChallenge computes the Fiat-Shamir challenge
+// of a proof of plaintext knowledge
+func (pk *PublicKey) plaintextKnowledgeChallenge(ct *Ciphertext, nonce *Ciphertext, context string) *big.Int {
+	t := pk.newTranscript("bgn/plaintext-knowledge")
+	t.AppendMessage("context", []byte(context))
+	t.AppendCiphertext("ct", ct)
+	t.AppendCiphertext("nonce", nonce)
+	return t.Challenge("c", pk.N)
+}
+
+// correctDecryptionChallenge computes the Fiat-Shamir challenge
+// of a proof of correct decryption
+func (pk *PublicKey) correctDecryptionChallenge(ct *Ciphertext, v *big.Int, A *pbc.Element, B *pbc.Element) *big.Int {
+	t := pk.newTranscript("bgn/correct-decryption")
+	t.AppendElement("vk", pk.DecryptionVK)
+	t.AppendCiphertext("ct", ct)
+	t.AppendInt("value", v)
+	t.AppendElement("A", A)
+	t.AppendElement("B", B)
+	return t.Challenge("c", pk.N)
+}
+
+// multiplicationChallenge computes the Fiat-Shamir challenge
+// of a proof of correct multiplication
+func (pk *PublicKey) multiplicationChallenge(ct1 *Ciphertext, ct2 *Ciphertext, res *Ciphertext, A *pbc.Element) *big.Int {
+	t := pk.newTranscript("bgn/multiplication")
+	t.AppendCiphertext("ct1", ct1)
+	t.AppendCiphertext("ct2", ct2)
+	t.AppendCiphertext("res", res)
+	t.AppendElement("A", A)
+	return t.Challenge("c", pk.N)
+}
+
+// rerandomizationChallenge computes the Fiat-Shamir challenge
+// of a proof of correct re-randomization
+func (pk *PublicKey) rerandomizationChallenge(ct *Ciphertext, res *Ciphertext, A *pbc.Element) *big.Int {
+	t := pk.newTranscript("bgn/rerandomization")
+	t.AppendCiphertext("ct", ct)
+	t.AppendCiphertext("res", res)
+	t.AppendElement("A", A)
+	return t.Challenge("c", pk.N)
+}
 
-	return new(big.Int).SetBytes(hash)
+// bitChallenge computes the Fiat-Shamir challenge of a bit proof
+func (pk *PublicKey) bitChallenge(ct *Ciphertext, A0 *pbc.Element, A1 *pbc.Element) *big.Int {
+	t := pk.newTranscript("bgn/bit")
+	t.AppendCiphertext("ct", ct)
+	t.AppendElement("A0", A0)
+	t.AppendElement("A1", A1)
+	return t.Challenge("c", pk.N)
 }
@@ -77,9 +77,9 @@ func TestProofOfPlaintextKnowledgeValid(t *testing.T) {
 	v := newCryptoRandom(pk.N)
 	ct := pk.EncryptWithRandomness(v, r)
 
-	proof := pk.NewProofOfPlaintextKnowledge(sk, v, r)
+	proof := pk.NewProofOfPlaintextKnowledge(v, r, "session")
 
-	if !pk.CheckProofOfPlaintextKnoewledge(ct, proof) {
+	if !pk.CheckProofOfPlaintextKnoewledge(ct, proof, "session") {
 		t.Fail()
 	}
 
@@ -94,21 +94,277 @@ func TestProofOfPlaintextKnowledgeBad(t *testing.T) {
 	v := newCryptoRandom(pk.N)
 	ct := pk.EncryptWithRandomness(v, r)
 
-	proof := pk.NewProofOfPlaintextKnowledge(sk, v, r2) // wrong randomness
+	proof := pk.NewProofOfPlaintextKnowledge(v, r2, "session") // wrong randomness
 
-	if pk.CheckProofOfPlaintextKnoewledge(ct, proof) {
+	if pk.CheckProofOfPlaintextKnoewledge(ct, proof, "session") {
 		t.Fail()
 	}
 
-	proof = pk.NewProofOfPlaintextKnowledge(sk, r2, r) // wrong value
+	proof = pk.NewProofOfPlaintextKnowledge(r2, r, "session") // wrong value
 
-	if pk.CheckProofOfPlaintextKnoewledge(ct, proof) {
+	if pk.CheckProofOfPlaintextKnoewledge(ct, proof, "session") {
 		t.Fail()
 	}
+
+	proof = pk.NewProofOfPlaintextKnowledge(v, r, "session")
+
+	if pk.CheckProofOfPlaintextKnoewledge(ct, proof, "other session") { // wrong context
+		t.Fail()
+	}
+}
+
+func TestBatchCheckProofsOfPlaintextKnowledge(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	cts := make([]*Ciphertext, 10)
+	proofs := make([]*ProofOfPlaintextKnowledge, 10)
+	for i := range cts {
+		v := big.NewInt(int64(i))
+		r := newCryptoRandom(pk.N)
+		cts[i] = pk.EncryptWithRandomness(v, r)
+		proofs[i] = pk.NewProofOfPlaintextKnowledge(v, r, "session")
+	}
+
+	invalid, err := pk.BatchCheckProofsOfPlaintextKnowledge(cts, proofs, "session")
+	if err != nil || len(invalid) != 0 {
+		t.Fatalf("Valid batch rejected: %v %v", invalid, err)
+	}
+
+	// proofs for the wrong ciphertext, a wrong randomness and a missing nonce
+	proofs[2] = proofs[1]
+	proofs[7] = pk.NewProofOfPlaintextKnowledge(big.NewInt(7), newCryptoRandom(pk.N), "session")
+	proofs[9] = &ProofOfPlaintextKnowledge{Ct: cts[9], V: big.NewInt(1), Z: big.NewInt(1)}
+
+	invalid, err = pk.BatchCheckProofsOfPlaintextKnowledge(cts, proofs, "session")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	expected := []int{2, 7, 9}
+	if len(invalid) != len(expected) {
+		t.Fatalf("Expected invalid proofs %v got %v", expected, invalid)
+	}
+	for i := range expected {
+		if invalid[i] != expected[i] {
+			t.Fatalf("Expected invalid proofs %v got %v", expected, invalid)
+		}
+	}
+
+	if _, err := pk.BatchCheckProofsOfPlaintextKnowledge(cts[1:], proofs, "session"); err == nil {
+		t.Errorf("Mismatched lengths accepted")
+	}
+}
+
+func TestBatchCheckProofsOfPlaintextKnowledgeOutOfSubgroup(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	cts := make([]*Ciphertext, 6)
+	data := make([][]byte, 6)
+	for i := range cts {
+		v := big.NewInt(int64(i))
+		r := newCryptoRandom(pk.N)
+		cts[i] = pk.EncryptWithRandomness(v, r)
+
+		proof := pk.NewProofOfPlaintextKnowledge(v, r, "session")
+		if i == 4 {
+			// a small order component would vanish under an even batch weight
+			proof.Nonce.C.Mul(proof.Nonce.C, outOfSubgroupElement(t, pk))
+
+			if pk.CheckProofOfPlaintextKnoewledge(cts[i], proof, "session") {
+				t.Errorf("Proof with a nonce outside the subgroup accepted")
+			}
+		}
+
+		data[i], _ = proof.Bytes()
+	}
+
+	// proofs received over the network are decoded before being batch checked
+	proofs := make([]*ProofOfPlaintextKnowledge, 6)
+	for i := range data {
+		proof, err := pk.NewProofOfPlaintextKnowledgeFromBytes(data[i])
+		if err != nil && i != 4 {
+			t.Fatalf("%v", err)
+		}
+		proofs[i] = proof
+	}
+
+	if proofs[4] != nil {
+		t.Fatalf("Proof with a nonce outside the subgroup decoded")
+	}
+
+	invalid, err := pk.BatchCheckProofsOfPlaintextKnowledge(cts, proofs, "session")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if len(invalid) != 1 || invalid[0] != 4 {
+		t.Errorf("Expected invalid proofs [4] got %v", invalid)
+	}
+}
+
+func TestBitProofValid(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	for _, v := range []int64{0, 1} {
+		r := newCryptoRandom(pk.N)
+		ct := pk.EncryptWithRandomness(big.NewInt(v), r)
+
+		proof := pk.NewBitProof(big.NewInt(v), r)
+
+		if !pk.VerifyBitProof(ct, proof) {
+			t.Errorf("Valid proof for %d rejected", v)
+		}
+	}
+}
+
+func TestBitProofBad(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	r := newCryptoRandom(pk.N)
+	proof := pk.NewBitProof(big.NewInt(1), r)
+
+	// proof does not transfer to an encryption of 2
+	ct := pk.EncryptWithRandomness(big.NewInt(2), r)
+	if pk.VerifyBitProof(ct, proof) {
+		t.Errorf("Proof accepted for an encryption of 2")
+	}
+
+	// proof does not transfer to different randomness
+	ct = pk.EncryptWithRandomness(big.NewInt(1), newCryptoRandom(pk.N))
+	if pk.VerifyBitProof(ct, proof) {
+		t.Errorf("Proof accepted for a different ciphertext")
+	}
+
+	// tampered challenges are rejected
+	ct = pk.EncryptWithRandomness(big.NewInt(1), r)
+	proof.C0 = new(big.Int).Add(proof.C0, big.NewInt(1))
+	if pk.VerifyBitProof(ct, proof) {
+		t.Errorf("Proof with tampered challenge accepted")
+	}
+}
+
+func TestCorrectDecryptionProofValid(t *testing.T) {
+
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	c1 := pk.Encrypt(big.NewInt(9))
+	c2 := pk.Encrypt(big.NewInt(-4))
+
+	for _, ct := range []*Ciphertext{c1, c2, pk.Mult(c1, c2)} {
+		proof, err := sk.NewCorrectDecryptionProof(pk, ct)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if !pk.VerifyCorrectDecryptionProof(ct, proof) {
+			t.Errorf("Valid proof for %v rejected", proof.Value)
+		}
+	}
+}
+
+func TestCorrectDecryptionProofBad(t *testing.T) {
+
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	c1 := pk.Encrypt(big.NewInt(9))
+	c2 := pk.Mult(c1, pk.Encrypt(big.NewInt(2)))
+
+	for _, ct := range []*Ciphertext{c1, c2} {
+		proof, _ := sk.NewCorrectDecryptionProof(pk, ct)
+
+		proof.Value = new(big.Int).Add(proof.Value, big.NewInt(1)) // wrong value
+		if pk.VerifyCorrectDecryptionProof(ct, proof) {
+			t.Errorf("Proof with wrong value accepted")
+		}
+	}
+
+	// proof does not transfer to a public key with a different secret key
+	pk2, sk2, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk2.SetupDecryption(sk2)
+
+	proof, _ := sk.NewCorrectDecryptionProof(pk, c1)
+	pk.DecryptionVK = pk.Pairing.NewGT().SetBytes(pk2.DecryptionVK.Bytes())
+	if pk.VerifyCorrectDecryptionProof(c1, proof) {
+		t.Errorf("Proof accepted under a different verification key")
+	}
+}
+
+func TestMultiplicationProofValid(t *testing.T) {
+
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	c1 := pk.Encrypt(big.NewInt(3))
+	c2 := pk.Encrypt(big.NewInt(5))
+	r := newCryptoRandom(pk.N)
+
+	res := pk.MultWithRandomness(c1, c2, r)
+	proof := pk.NewMultiplicationProof(c1, c2, r)
+
+	if !pk.VerifyMultiplicationProof(c1, c2, res, proof) {
+		t.Errorf("Valid proof rejected")
+	}
+
+	if v, _ := sk.Decrypt(res, pk); v.Cmp(big.NewInt(15)) != 0 {
+		t.Errorf("Expected: 15 got: %v", v)
+	}
+}
+
+func TestMultiplicationProofBad(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	c1 := pk.Encrypt(big.NewInt(3))
+	c2 := pk.Encrypt(big.NewInt(5))
+	r := newCryptoRandom(pk.N)
+	proof := pk.NewMultiplicationProof(c1, c2, r)
+
+	// product of different ciphertexts
+	res := pk.MultWithRandomness(c1, pk.Encrypt(big.NewInt(5)), r)
+	if pk.VerifyMultiplicationProof(c1, c2, res, proof) {
+		t.Errorf("Proof accepted for a different product")
+	}
+
+	// product shifted by an encryption of one
+	res = pk.MultWithRandomness(c1, c2, r)
+	res = pk.Add(res, pk.makeL2(pk.EncryptDeterministic(big.NewInt(1))))
+	if pk.VerifyMultiplicationProof(c1, c2, res, proof) {
+		t.Errorf("Proof accepted for an incorrect product")
+	}
+}
+
+func TestRerandomizationProof(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	c1 := pk.Encrypt(big.NewInt(3))
+	c2 := pk.Mult(c1, pk.Encrypt(big.NewInt(2)))
+
+	for _, ct := range []*Ciphertext{c1, c2} {
+		r := newCryptoRandom(pk.N)
+		res := pk.RerandomizeWithRandomness(ct, r)
+		proof := pk.NewRerandomizationProof(ct, r)
+
+		if !pk.VerifyRerandomizationProof(ct, res, proof) {
+			t.Errorf("[L2=%v] Valid proof rejected", ct.L2)
+		}
+
+		// a re-randomization of a different value
+		other := pk.Add(res, pk.EncryptDeterministic(big.NewInt(1)))
+		if pk.VerifyRerandomizationProof(ct, other, proof) {
+			t.Errorf("[L2=%v] Proof accepted for a different value", ct.L2)
+		}
+	}
 }
 
 func BenchmarkProofOfPlaintextKnowledgeGen(b *testing.B) {
-	pk, sk, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk, _, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
 	if err != nil {
 		panic(err)
 	}
@@ -118,12 +374,12 @@ func BenchmarkProofOfPlaintextKnowledgeGen(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		pk.NewProofOfPlaintextKnowledge(sk, v, r)
+		pk.NewProofOfPlaintextKnowledge(v, r, "session")
 	}
 }
 
 func BenchmarkProofOfPlaintextKnowledgeVerify(b *testing.B) {
-	pk, sk, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk, _, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
 	if err != nil {
 		panic(err)
 	}
@@ -131,10 +387,31 @@ func BenchmarkProofOfPlaintextKnowledgeVerify(b *testing.B) {
 	r := newCryptoRandom(pk.N)
 	v := newCryptoRandom(pk.N)
 	ct := pk.EncryptWithRandomness(v, r)
-	proof := pk.NewProofOfPlaintextKnowledge(sk, v, r)
+	proof := pk.NewProofOfPlaintextKnowledge(v, r, "session")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pk.CheckProofOfPlaintextKnoewledge(ct, proof, "session")
+	}
+}
+
+func BenchmarkBatchCheckProofsOfPlaintextKnowledge(b *testing.B) {
+	pk, _, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		panic(err)
+	}
+
+	cts := make([]*Ciphertext, 100)
+	proofs := make([]*ProofOfPlaintextKnowledge, 100)
+	for i := range cts {
+		v := newCryptoRandom(pk.N)
+		r := newCryptoRandom(pk.N)
+		cts[i] = pk.EncryptWithRandomness(v, r)
+		proofs[i] = pk.NewProofOfPlaintextKnowledge(v, r, "session")
+	}
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		pk.CheckProofOfPlaintextKnoewledge(ct, proof)
+		pk.BatchCheckProofsOfPlaintextKnowledge(cts, proofs, "session")
 	}
 }
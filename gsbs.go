@@ -50,14 +50,27 @@ func (pk *PublicKey) PrecomputeTables(genG1 *pbc.Element, genGT *pbc.Element) {
 	tablesComputed = true
 }
 
-// obtain the discrete log in O(sqrt(T)) time using giant step baby step algorithm
-func (pk *PublicKey) getDL(csk *pbc.Element, gsk *pbc.Element, l2 bool) (*big.Int, error) {
+// precomputeTableGT builds only the level2 table, for decryption
+// bases that have no level1 counterpart (e.g. threshold decryption)
+func (pk *PublicKey) precomputeTableGT(genGT *pbc.Element) {
+
+	bound := int64(math.Ceil(math.Sqrt(float64(pk.MsgSpace.Int64())))) + 1
+	computeTableGT(genGT, bound)
+
+	tablesComputed = true
+}
+
+// obtain the discrete log in O(sqrt(T)) time using giant step baby step algorithm.
+// The number of giant steps is multiplied by window, which searches
+// a range window times larger than the message space using the same tables
+func (pk *PublicKey) getDL(csk *pbc.Element, gsk *pbc.Element, l2 bool, window int64) (*big.Int, error) {
 
 	if !tablesComputed {
 		panic("DL tables not computed!")
 	}
 
 	bound := int64(math.Ceil(math.Sqrt(float64(pk.MsgSpace.Int64()))))
+	steps := bound * window
 
 	aux := csk.NewFieldElement()
 
@@ -74,7 +87,7 @@ func (pk *PublicKey) getDL(csk *pbc.Element, gsk *pbc.Element, l2 bool) (*big.In
 	var val *big.Int
 	var found bool
 
-	for i := int64(0); i <= bound; i++ {
+	for i := int64(0); i <= steps; i++ {
 
 		found = false
 		val = big.NewInt(0)
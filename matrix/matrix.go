@@ -0,0 +1,311 @@
+package matrix
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/sachaservan/bgn"
+)
+
+// Vector is a vector of BGN ciphertexts
+type Vector struct {
+	Entries []*bgn.Ciphertext
+}
+
+// Matrix is a row-major matrix of BGN ciphertexts
+type Matrix struct {
+	Entries [][]*bgn.Ciphertext
+}
+
+// vectorWrapper is a wrapper for the Vector struct
+// for marshalling/unmarshalling purposes
+type vectorWrapper struct {
+	Entries [][]byte
+}
+
+// matrixWrapper is a wrapper for the Matrix struct
+// for marshalling/unmarshalling purposes
+type matrixWrapper struct {
+	Entries [][][]byte
+}
+
+// NewVector generates a vector from the provided ciphertexts
+func NewVector(entries []*bgn.Ciphertext) *Vector {
+	return &Vector{entries}
+}
+
+// NewMatrix generates a matrix from the provided rows of ciphertexts
+func NewMatrix(entries [][]*bgn.Ciphertext) *Matrix {
+	for _, row := range entries {
+		if len(row) != len(entries[0]) {
+			panic("Attempting to create a matrix with rows of different lengths")
+		}
+	}
+
+	return &Matrix{entries}
+}
+
+// Len returns the number of entries in the vector
+func (v *Vector) Len() int {
+	return len(v.Entries)
+}
+
+// Rows returns the number of rows in the matrix
+func (m *Matrix) Rows() int {
+	return len(m.Entries)
+}
+
+// Cols returns the number of columns in the matrix
+func (m *Matrix) Cols() int {
+	if len(m.Entries) == 0 {
+		return 0
+	}
+
+	return len(m.Entries[0])
+}
+
+// EncryptVector encrypts each value under the public key pk
+func EncryptVector(pk *bgn.PublicKey, values []*big.Int) *Vector {
+
+	entries := make([]*bgn.Ciphertext, len(values))
+	for i, v := range values {
+		entries[i] = pk.Encrypt(v)
+	}
+
+	return &Vector{entries}
+}
+
+// EncryptMatrix encrypts each value under the public key pk
+func EncryptMatrix(pk *bgn.PublicKey, values [][]*big.Int) *Matrix {
+
+	entries := make([][]*bgn.Ciphertext, len(values))
+	for i, row := range values {
+		entries[i] = EncryptVector(pk, row).Entries
+	}
+
+	return NewMatrix(entries)
+}
+
+// DecryptVector uses the secret key to recover every entry of the vector
+func DecryptVector(sk *bgn.SecretKey, pk *bgn.PublicKey, v *Vector) ([]*big.Int, error) {
+
+	values := make([]*big.Int, v.Len())
+	for i, ct := range v.Entries {
+		value, err := sk.Decrypt(ct, pk)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// DecryptMatrix uses the secret key to recover every entry of the matrix
+func DecryptMatrix(sk *bgn.SecretKey, pk *bgn.PublicKey, m *Matrix) ([][]*big.Int, error) {
+
+	values := make([][]*big.Int, m.Rows())
+	for i, row := range m.Entries {
+		decrypted, err := DecryptVector(sk, pk, &Vector{row})
+		if err != nil {
+			return nil, err
+		}
+		values[i] = decrypted
+	}
+
+	return values, nil
+}
+
+// AddVectors homomorphically adds two encrypted vectors entry-wise
+func AddVectors(pk *bgn.PublicKey, a *Vector, b *Vector) *Vector {
+
+	if a.Len() != b.Len() {
+		panic("Attempting to add vectors of different lengths")
+	}
+
+	entries := make([]*bgn.Ciphertext, a.Len())
+	for i := range a.Entries {
+		entries[i] = pk.Add(a.Entries[i], b.Entries[i])
+	}
+
+	return &Vector{entries}
+}
+
+// AddMatrices homomorphically adds two encrypted matrices entry-wise
+func AddMatrices(pk *bgn.PublicKey, a *Matrix, b *Matrix) *Matrix {
+
+	if a.Rows() != b.Rows() || a.Cols() != b.Cols() {
+		panic("Attempting to add matrices of different dimensions")
+	}
+
+	entries := make([][]*bgn.Ciphertext, a.Rows())
+	for i := range a.Entries {
+		entries[i] = AddVectors(pk, &Vector{a.Entries[i]}, &Vector{b.Entries[i]}).Entries
+	}
+
+	return &Matrix{entries}
+}
+
+// MultPlainMatrix homomorphically multiplies the plaintext matrix m
+// with the encrypted vector v and returns the encrypted vector m*v
+func MultPlainMatrix(pk *bgn.PublicKey, m [][]*big.Int, v *Vector) *Vector {
+
+	entries := make([]*bgn.Ciphertext, len(m))
+
+	var wg sync.WaitGroup
+	for i, row := range m {
+		if len(row) != v.Len() {
+			panic("Attempting to multiply a matrix and a vector of incompatible dimensions")
+		}
+
+		wg.Add(1)
+		go func(i int, row []*big.Int) {
+			defer wg.Done()
+			entries[i] = pk.InnerProductPlain(v.Entries, row)
+		}(i, row)
+	}
+	wg.Wait()
+
+	return &Vector{entries}
+}
+
+// OuterProduct homomorphically computes the outer product of two
+// level1 encrypted vectors, resulting in a matrix of level2 ciphertexts
+func OuterProduct(pk *bgn.PublicKey, a *Vector, b *Vector) *Matrix {
+
+	entries := make([][]*bgn.Ciphertext, a.Len())
+
+	var wg sync.WaitGroup
+	for i := range a.Entries {
+		entries[i] = make([]*bgn.Ciphertext, b.Len())
+		for j := range b.Entries {
+			wg.Add(1)
+			go func(i, j int) {
+				defer wg.Done()
+				entries[i][j] = pk.Mult(a.Entries[i], b.Entries[j])
+			}(i, j)
+		}
+	}
+	wg.Wait()
+
+	return &Matrix{entries}
+}
+
+// Bytes returns the marshalled bytes of the vector
+func (v *Vector) Bytes() ([]byte, error) {
+
+	w := vectorWrapper{}
+	w.Entries = make([][]byte, v.Len())
+
+	for i, ct := range v.Entries {
+		data, err := ct.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		w.Entries[i] = data
+	}
+
+	// use default gob encoder
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(w); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Bytes returns the marshalled bytes of the matrix
+func (m *Matrix) Bytes() ([]byte, error) {
+
+	w := matrixWrapper{}
+	w.Entries = make([][][]byte, m.Rows())
+
+	for i, row := range m.Entries {
+		w.Entries[i] = make([][]byte, len(row))
+		for j, ct := range row {
+			data, err := ct.Bytes()
+			if err != nil {
+				return nil, err
+			}
+			w.Entries[i][j] = data
+		}
+	}
+
+	// use default gob encoder
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(w); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// NewVectorFromBytes generates a vector from a marshalled vector.
+// Requires the public key in order to ensure the correct pairing is used
+func NewVectorFromBytes(pk *bgn.PublicKey, data []byte) (*Vector, error) {
+
+	if len(data) == 0 {
+		return nil, errors.New("no data provided")
+	}
+
+	w := vectorWrapper{}
+
+	reader := bytes.NewReader(data)
+	dec := gob.NewDecoder(reader)
+	if err := dec.Decode(&w); err != nil {
+		return nil, err
+	}
+
+	entries := make([]*bgn.Ciphertext, len(w.Entries))
+	for i, ctBytes := range w.Entries {
+		ct, err := pk.NewCiphertextFromBytes(ctBytes)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = ct
+	}
+
+	return &Vector{entries}, nil
+}
+
+// NewMatrixFromBytes generates a matrix from a marshalled matrix.
+// Requires the public key in order to ensure the correct pairing is used
+func NewMatrixFromBytes(pk *bgn.PublicKey, data []byte) (*Matrix, error) {
+
+	if len(data) == 0 {
+		return nil, errors.New("no data provided")
+	}
+
+	w := matrixWrapper{}
+
+	reader := bytes.NewReader(data)
+	dec := gob.NewDecoder(reader)
+	if err := dec.Decode(&w); err != nil {
+		return nil, err
+	}
+
+	entries := make([][]*bgn.Ciphertext, len(w.Entries))
+	for i, row := range w.Entries {
+		entries[i] = make([]*bgn.Ciphertext, len(row))
+		for j, ctBytes := range row {
+			ct, err := pk.NewCiphertextFromBytes(ctBytes)
+			if err != nil {
+				return nil, err
+			}
+			entries[i][j] = ct
+		}
+	}
+
+	for _, row := range entries {
+		if len(row) != len(entries[0]) {
+			return nil, errors.New("rows of different lengths")
+		}
+	}
+
+	return &Matrix{entries}, nil
+}
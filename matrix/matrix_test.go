@@ -0,0 +1,155 @@
+package matrix
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/bgn"
+)
+
+const KEYBITS = 512
+const POLYBASE = 3
+const MSGSPACE = 1021
+const FPSCALEBASE = 3
+const FPPREC = 0.0001
+const DET = true // deterministic ops
+
+func toBigInts(values []int64) []*big.Int {
+	res := make([]*big.Int, len(values))
+	for i, v := range values {
+		res[i] = big.NewInt(v)
+	}
+
+	return res
+}
+
+func TestMultPlainMatrix(t *testing.T) {
+
+	pk, sk, err := bgn.NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	pk.SetupDecryption(sk)
+
+	m := [][]*big.Int{
+		toBigInts([]int64{1, 2, 3}),
+		toBigInts([]int64{-1, 0, 4}),
+	}
+	v := EncryptVector(pk, toBigInts([]int64{2, 5, 1}))
+
+	res := MultPlainMatrix(pk, m, v)
+	actual, err := DecryptVector(sk, pk, res)
+	if err != nil {
+		t.Fatalf("Error when decrypting %v\n", err.Error())
+	}
+
+	expected := toBigInts([]int64{15, 2})
+	for i := range expected {
+		if actual[i].Cmp(expected[i]) != 0 {
+			t.Errorf("Entry %d: expected %v got %v\n", i, expected[i], actual[i])
+		}
+	}
+}
+
+func TestOuterProduct(t *testing.T) {
+
+	pk, sk, err := bgn.NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	pk.SetupDecryption(sk)
+
+	x := []int64{1, -2, 3}
+	y := []int64{4, 5}
+	a := EncryptVector(pk, toBigInts(x))
+	b := EncryptVector(pk, toBigInts(y))
+
+	res := OuterProduct(pk, a, b)
+	if res.Rows() != len(x) || res.Cols() != len(y) {
+		t.Fatalf("Expected %dx%d matrix got %dx%d\n", len(x), len(y), res.Rows(), res.Cols())
+	}
+
+	actual, err := DecryptMatrix(sk, pk, res)
+	if err != nil {
+		t.Fatalf("Error when decrypting %v\n", err.Error())
+	}
+
+	for i := range x {
+		for j := range y {
+			if actual[i][j].Int64() != x[i]*y[j] {
+				t.Errorf("Entry (%d, %d): expected %d got %v\n", i, j, x[i]*y[j], actual[i][j])
+			}
+		}
+	}
+}
+
+func TestAddMatrices(t *testing.T) {
+
+	pk, sk, err := bgn.NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	pk.SetupDecryption(sk)
+
+	a := EncryptMatrix(pk, [][]*big.Int{toBigInts([]int64{1, 2}), toBigInts([]int64{3, 4})})
+	b := EncryptMatrix(pk, [][]*big.Int{toBigInts([]int64{5, 6}), toBigInts([]int64{7, -8})})
+
+	actual, err := DecryptMatrix(sk, pk, AddMatrices(pk, a, b))
+	if err != nil {
+		t.Fatalf("Error when decrypting %v\n", err.Error())
+	}
+
+	expected := [][]int64{{6, 8}, {10, -4}}
+	for i := range expected {
+		for j := range expected[i] {
+			if actual[i][j].Int64() != expected[i][j] {
+				t.Errorf("Entry (%d, %d): expected %d got %v\n", i, j, expected[i][j], actual[i][j])
+			}
+		}
+	}
+}
+
+func TestMatrixToFromBytes(t *testing.T) {
+
+	pk, _, err := bgn.NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	a := EncryptVector(pk, toBigInts([]int64{1, 2}))
+	expected := OuterProduct(pk, a, a)
+
+	data, err := expected.Bytes()
+	if err != nil {
+		t.Fatalf("Error when encoding matrix to bytes %v\n", err.Error())
+	}
+
+	recovered, err := NewMatrixFromBytes(pk, data)
+	if err != nil {
+		t.Fatalf("Error when recovering matrix from bytes %v\n", err.Error())
+	}
+
+	for i := range expected.Entries {
+		for j := range expected.Entries[i] {
+			if expected.Entries[i][j].String() != recovered.Entries[i][j].String() {
+				t.Fatalf("Incorrect recovery. Expected %v, got %v\n", expected.Entries[i][j], recovered.Entries[i][j])
+			}
+		}
+	}
+
+	data, err = a.Bytes()
+	if err != nil {
+		t.Fatalf("Error when encoding vector to bytes %v\n", err.Error())
+	}
+
+	recoveredVec, err := NewVectorFromBytes(pk, data)
+	if err != nil {
+		t.Fatalf("Error when recovering vector from bytes %v\n", err.Error())
+	}
+
+	for i := range a.Entries {
+		if a.Entries[i].String() != recoveredVec.Entries[i].String() {
+			t.Fatalf("Incorrect recovery. Expected %v, got %v\n", a.Entries[i], recoveredVec.Entries[i])
+		}
+	}
+}
@@ -1,14 +1,15 @@
 package bgn
 
 import (
-	"math"
+	"errors"
+	"fmt"
 	"math/big"
 )
 
-var degreeSumTable []*big.Int
-var degreeTable []*big.Int
+const defaultDegreeBound = 128 // note: 3^64 > Int64 hence this is a generous upper bound
 
-const degreeBound = 128 // note: 3^64 > Int64 hence this is a generous upper bound
+var errDegreeBound = errors.New("value cannot be encoded within the degree bound")
+var errPolyBase = errors.New("value cannot be encoded in the polynomial base")
 
 // PolyPlaintext is a polynomial encoded value
 type PolyPlaintext struct {
@@ -16,6 +17,7 @@ type PolyPlaintext struct {
 	Coefficients []*big.Int // coefficients in the polynomial
 	Degree       int        // degree of the polynomial
 	ScaleFactor  int
+	Denominator  *big.Int // exact divisor applied by PolyEval (nil for 1)
 }
 
 // Plaintext struct holds data related to the polynomial encoded plaintext
@@ -31,84 +33,212 @@ func (pk *PublicKey) NewPlaintext(m *big.Int) *Plaintext {
 
 // NewUnbalancedPlaintext generates an unbalanced base b encoded polynomial representation of m
 // fpp is the starting floating point scale factor which determines the precision
-func (pk *PublicKey) NewUnbalancedPlaintext(m *big.Float) *PolyPlaintext {
+func (pk *PublicKey) NewUnbalancedPlaintext(m *big.Float) (*PolyPlaintext, error) {
 
-	if degreeTable == nil {
-		panic("Encoding tables not computed!")
+	r, err := floatToRat(m)
+	if err != nil {
+		return nil, err
 	}
 
-	mFloat, _ := m.Float64()
-	// m is a rational number, encode it rationally
-	if math.Remainder(mFloat, 1.0) != 0.0 {
-		numerator, scaleFactor := rationalize(
-			mFloat-math.Floor(mFloat),
-			pk.PolyEncodingParams.FPScaleBase,
-			pk.PolyEncodingParams.FPPrecision,
-		)
+	pt, _, err := pk.newPlaintextFromRat(r, false, false)
+	return pt, err
+}
 
-		mInt := big.NewInt(0)
-		m.Int(mInt)
-		mInt.Mul(mInt, big.NewInt(int64(math.Pow(float64(pk.PolyEncodingParams.FPScaleBase), float64(scaleFactor)))))
-		mInt.Add(mInt, big.NewInt(numerator))
+// NewPolyPlaintext generates an balanced base b encoded polynomial representation of m
+// fpp is the starting floating point scale factor which determines the precision
+func (pk *PublicKey) NewPolyPlaintext(m *big.Float) (*PolyPlaintext, error) {
 
-		coeffs, degree := unbalancedEncode(mInt, pk.PolyEncodingParams.PolyBase, degreeTable, degreeSumTable)
-		return &PolyPlaintext{pk, coeffs, degree, scaleFactor}
+	r, err := floatToRat(m)
+	if err != nil {
+		return nil, err
 	}
 
-	// m is a big.Int
-	mInt := big.NewInt(0)
-	m.Int(mInt)
-	coeffs, degree := unbalancedEncode(mInt, pk.PolyEncodingParams.PolyBase, degreeTable, degreeSumTable)
-	return &PolyPlaintext{pk, coeffs, degree, 0}
+	pt, _, err := pk.newPlaintextFromRat(r, true, false)
+	return pt, err
 }
 
-// NewPolyPlaintext generates an balanced base b encoded polynomial representation of m
-// fpp is the starting floating point scale factor which determines the precision
-func (pk *PublicKey) NewPolyPlaintext(m *big.Float) *PolyPlaintext {
+// NewUnbalancedPlaintextFromRat generates an unbalanced base b encoded polynomial
+// representation of the rational m. See NewPolyPlaintextFromRat
+func (pk *PublicKey) NewUnbalancedPlaintextFromRat(m *big.Rat) (*PolyPlaintext, *big.Rat, error) {
+	return pk.newPlaintextFromRat(m, false, true)
+}
+
+// NewPolyPlaintextFromRat generates a balanced base b encoded polynomial
+// representation of the rational m. The encoding is exact whenever m has a finite
+// expansion in FPScaleBase and is otherwise rounded to within FPPrecision.
+// Also returns the rounding error (encoded value - m), which is zero if exact.
+// Fails if the encoding does not fit within the degree bound
+func (pk *PublicKey) NewPolyPlaintextFromRat(m *big.Rat) (*PolyPlaintext, *big.Rat, error) {
+	return pk.newPlaintextFromRat(m, true, true)
+}
+
+// NewPolyPlaintextFromString generates a balanced base b encoded polynomial
+// representation of a decimal (e.g. "1234.5678" or "-1.5e-3") or fractional
+// (e.g. "3/4") string. See NewPolyPlaintextFromRat
+func (pk *PublicKey) NewPolyPlaintextFromString(s string) (*PolyPlaintext, *big.Rat, error) {
+
+	m, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, nil, fmt.Errorf("cannot parse %q as a number", s)
+	}
+
+	return pk.NewPolyPlaintextFromRat(m)
+}
+
+func (pk *PublicKey) newPlaintextFromRat(m *big.Rat, balanced bool, exact bool) (*PolyPlaintext, *big.Rat, error) {
+
+	if pk.degreeTable == nil {
+		return nil, nil, errors.New("encoding tables not computed")
+	}
+
+	// encode |m| and negate the coefficients
+	if m.Sign() < 0 {
+		pt, roundingErr, err := pk.newPlaintextFromRat(new(big.Rat).Neg(m), balanced, exact)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		negateCoefficients(pt.Coefficients)
+		return pt, roundingErr.Neg(roundingErr), nil
+	}
+
+	var mInt *big.Int
+	var scaleFactor int
+	ok := false
 
-	if degreeTable == nil {
-		panic("Encoding tables not computed!")
+	if exact {
+		mInt, scaleFactor, ok = pk.exactFixedPoint(m)
 	}
 
-	if m.Cmp(big.NewFloat(0)) < 0 {
-		panic("negative encodings not implemented")
+	if !ok {
+		mInt, scaleFactor = pk.fixedPoint(m)
 	}
 
-	// TODO: don't convert to float64
-	mFloat, _ := m.Float64()
+	var coeffs []*big.Int
+	var degree int
+	var err error
+	if balanced {
+		coeffs, degree, err = balancedEncode(mInt, pk.PolyEncodingParams.PolyBase, pk.degreeTable, pk.degreeSumTable)
+	} else {
+		coeffs, degree, err = unbalancedEncode(mInt, pk.PolyEncodingParams.PolyBase, pk.degreeTable, pk.degreeSumTable)
+	}
 
-	// m is a rational number, encode it rationally
-	if math.Remainder(mFloat, 1.0) != 0.0 {
+	if err != nil {
+		return nil, nil, err
+	}
 
-		numerator, scaleFactor := rationalize(
-			mFloat-math.Floor(mFloat),
-			pk.PolyEncodingParams.FPScaleBase,
-			pk.PolyEncodingParams.FPPrecision,
-		)
+	pt := &PolyPlaintext{pk, coeffs, degree, scaleFactor, nil}
+	return pt, new(big.Rat).Sub(pt.PolyEvalRat(), m), nil
+}
 
-		mInt := big.NewInt(0)
-		m.Int(mInt)
-		mInt.Mul(mInt, big.NewInt(int64(math.Pow(float64(pk.PolyEncodingParams.FPScaleBase), float64(scaleFactor)))))
-		mInt.Add(mInt, big.NewInt(numerator))
+// exactFixedPoint computes the smallest scale factor s such that m * FPScaleBase^s
+// is an integer, and returns that integer along with s. Fails if m has no finite
+// expansion in FPScaleBase or if s would exceed the degree bound
+func (pk *PublicKey) exactFixedPoint(m *big.Rat) (*big.Int, int, bool) {
+
+	base := big.NewInt(int64(pk.PolyEncodingParams.FPScaleBase))
+	denom := new(big.Int).Set(m.Denom())
+	scale := big.NewInt(1)
+
+	// each multiplication by the base cancels gcd(denom, base) from the denominator
+	s := 0
+	for ; denom.Cmp(big.NewInt(1)) != 0; s++ {
+		g := new(big.Int).GCD(nil, nil, denom, base)
+		if g.Cmp(big.NewInt(1)) == 0 || s == len(pk.degreeTable) {
+			return nil, 0, false
+		}
 
-		coeffs, degree := balancedEncode(mInt, pk.PolyEncodingParams.PolyBase, degreeTable, degreeSumTable)
-		return &PolyPlaintext{pk, coeffs, degree, scaleFactor}
+		denom.Quo(denom, g)
+		scale.Mul(scale, base)
 	}
 
-	// m is an int
-	mInt := big.NewInt(0)
-	m.Int(mInt)
-	coeffs, degree := balancedEncode(mInt, pk.PolyEncodingParams.PolyBase, degreeTable, degreeSumTable)
-	return &PolyPlaintext{pk, coeffs, degree, 0}
+	num := new(big.Rat).Mul(m, new(big.Rat).SetInt(scale))
+	return num.Num(), s, true
+}
+
+// fixedPoint computes the smallest scale factor s such that round(m * FPScaleBase^s) / FPScaleBase^s
+// is within FPPrecision of m, and returns the rounded numerator along with s.
+// The scale factor never exceeds the degree bound so the search always terminates
+func (pk *PublicKey) fixedPoint(m *big.Rat) (*big.Int, int) {
+
+	precision := new(big.Rat)
+	if pk.PolyEncodingParams.FPPrecision > 0 {
+		precision.SetFloat64(pk.PolyEncodingParams.FPPrecision)
+	}
+
+	base := big.NewInt(int64(pk.PolyEncodingParams.FPScaleBase))
+	scale := big.NewInt(1)
+
+	for s := 0; ; s++ {
+		scaled := new(big.Rat).Mul(m, new(big.Rat).SetInt(scale))
+		num := roundRat(scaled)
+
+		// |num / scale - m| <= precision
+		diff := new(big.Rat).SetFrac(num, scale)
+		diff.Sub(diff, m)
+		if diff.Abs(diff).Cmp(precision) <= 0 || s == len(pk.degreeTable) {
+			return num, s
+		}
+
+		scale.Mul(scale, base)
+	}
+}
+
+// roundRat rounds r to the nearest integer (halves away from zero)
+func roundRat(r *big.Rat) *big.Int {
+
+	num := new(big.Int).Abs(r.Num())
+	num.Lsh(num, 1)
+	num.Add(num, r.Denom())
+
+	res := num.Quo(num, new(big.Int).Lsh(r.Denom(), 1))
+	if r.Sign() < 0 {
+		res.Neg(res)
+	}
+
+	return res
+}
+
+// floatToRat converts m to an exact rational
+func floatToRat(m *big.Float) (*big.Rat, error) {
+
+	if m.IsInf() {
+		return nil, errors.New("cannot encode an infinite value")
+	}
+
+	r, _ := m.Rat(nil)
+	return r, nil
+}
+
+// SetPolyEncodingParams replaces the message encoding parameters of the key
+// (e.g. to change the degree bound) and rebuilds its encoding tables.
+// Plaintexts and ciphertexts encoded under the previous parameters should not be mixed
+// with new ones. The parameters and tables are read without locking while encoding,
+// so this must not be called concurrently with encoding or other operations on the key
+func (pk *PublicKey) SetPolyEncodingParams(params *PolyEncodingParams) error {
+
+	if params.PolyBase < 2 || params.FPScaleBase < 2 {
+		return errors.New("encoding bases must be at least 2")
+	}
+
+	if params.DegreeBound < 0 {
+		return errors.New("degree bound must not be negative")
+	}
+
+	p := *params
+	pk.PolyEncodingParams = &p
+	pk.computeEncodingTable()
+
+	return nil
 }
 
 func (pk *PublicKey) computeEncodingTable() {
 
 	base := big.NewInt(int64(pk.PolyEncodingParams.PolyBase))
-	bound := degreeBound
+	bound := pk.PolyEncodingParams.degreeBound()
 
-	degreeTable = make([]*big.Int, bound)
-	degreeSumTable = make([]*big.Int, bound)
+	degreeTable := make([]*big.Int, bound)
+	degreeSumTable := make([]*big.Int, bound)
 
 	sum := big.NewInt(1)
 	degreeSumTable[0] = big.NewInt(1)
@@ -121,26 +251,34 @@ func (pk *PublicKey) computeEncodingTable() {
 		degreeSumTable[i] = big.NewInt(0)
 		degreeSumTable[i].Set(sum)
 	}
+
+	pk.degreeTable = degreeTable
+	pk.degreeSumTable = degreeSumTable
 }
 
 // compute the closest degree to the target value
-func degree(target *big.Int, sums []*big.Int, bound int, balanced bool) int {
+// returns -1 if the target is beyond the degree tables
+func degree(target *big.Int, degrees []*big.Int, sums []*big.Int, bound int, balanced bool) int {
 
-	if target.Int64() == 1 {
+	if target.Cmp(big.NewInt(1)) == 0 {
 		return 0
 	}
 
+	if bound >= len(sums) {
+		bound = len(sums) - 1
+	}
+
 	if balanced {
 
 		for i := 1; i <= bound; i++ {
-			if degreeSumTable[i].Cmp(target) >= 0 {
+			if sums[i].Cmp(target) >= 0 {
 				return i
 			}
 		}
 
 	} else {
 		for i := 1; i <= bound; i++ {
-			if degreeTable[i].Cmp(target) >= 1 {
+			if degrees[i].Cmp(target) >= 1 {
 				return i - 1
 			}
 		}
@@ -158,34 +296,49 @@ func toBigIntArray(values []int64) []*big.Int {
 	return res
 }
 
-func unbalancedEncode(target *big.Int, base int, degrees []*big.Int, sumDegrees []*big.Int) ([]*big.Int, int) {
+func unbalancedEncode(target *big.Int, base int, degrees []*big.Int, sumDegrees []*big.Int) ([]*big.Int, int, error) {
 
 	// special case
 	if target.Cmp(big.NewInt(0)) == 0 {
 		coefficients := make([]int64, 1)
 		coefficients[0] = 0
-		return toBigIntArray(coefficients), 1
+		return toBigIntArray(coefficients), 1, nil
 	}
 
-	if target.Cmp(big.NewInt(0)) < 0 {
-		panic("Negative encoding not supported")
+	// encode |target| and negate the coefficients
+	if target.Sign() < 0 {
+		coefficients, degree, err := unbalancedEncode(new(big.Int).Neg(target), base, degrees, sumDegrees)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		negateCoefficients(coefficients)
+		return coefficients, degree, nil
 	}
 
 	if sumDegrees == nil {
-		panic("No precomputed degree table!")
+		return nil, 0, errors.New("no precomputed degree table")
 	}
 
-	coefficients := make([]int64, degreeBound)
-	bound := len(sumDegrees)
-	lastDegree := degreeBound
+	coefficients := make([]int64, len(degrees))
+	bound := -1
+	lastDegree := len(degrees)
 
 	for {
 
-		index := degree(target, sumDegrees, lastDegree, false)
+		index := degree(target, degrees, sumDegrees, lastDegree, false)
+		if index < 0 {
+			return nil, 0, errDegreeBound
+		}
+
+		// each coefficient is set once, which only fails for bases above 3
+		if coefficients[index] != 0 {
+			return nil, 0, errPolyBase
+		}
 		lastDegree = index + 1
 
-		if bound == len(sumDegrees) {
-			bound = index + 1
+		if bound < 0 {
+			bound = index
 		}
 
 		value := degrees[index]
@@ -199,20 +352,20 @@ func unbalancedEncode(target *big.Int, base int, degrees []*big.Int, sumDegrees
 		}
 
 		if value.Cmp(target) == 0 {
-			return toBigIntArray(coefficients[:bound+1]), bound + 1
+			return toBigIntArray(coefficients[:bound+1]), bound + 1, nil
 		}
 
 		target.Sub(target, value)
 	}
 }
 
-func balancedEncode(target *big.Int, base int, degrees []*big.Int, sumDegrees []*big.Int) ([]*big.Int, int) {
+func balancedEncode(target *big.Int, base int, degrees []*big.Int, sumDegrees []*big.Int) ([]*big.Int, int, error) {
 
 	// special case
-	if target.Int64() == 0 {
+	if target.Sign() == 0 {
 		coefficients := make([]int64, 1)
 		coefficients[0] = 0
-		return toBigIntArray(coefficients), 1
+		return toBigIntArray(coefficients), 1, nil
 	}
 
 	isNegative := big.NewInt(0).Cmp(target) > 0
@@ -221,20 +374,28 @@ func balancedEncode(target *big.Int, base int, degrees []*big.Int, sumDegrees []
 	}
 
 	if sumDegrees == nil {
-		panic("No precomputed degree table!")
+		return nil, 0, errors.New("no precomputed degree table")
 	}
 
-	coefficients := make([]int64, degreeBound)
-	bound := len(sumDegrees)
-	lastIndex := degreeBound
+	coefficients := make([]int64, len(degrees))
+	bound := -1
+	lastIndex := len(degrees)
 	nextNegative := false
 
 	for {
 
-		index := degree(target, sumDegrees, lastIndex, true)
+		index := degree(target, degrees, sumDegrees, lastIndex, true)
+		if index < 0 {
+			return nil, 0, errDegreeBound
+		}
+
+		// each coefficient is set once, which only fails for bases above 3
+		if coefficients[index] != 0 {
+			return nil, 0, errPolyBase
+		}
 		lastIndex = index
 
-		if bound == len(sumDegrees) {
+		if bound < 0 {
 			bound = index
 		}
 
@@ -253,7 +414,7 @@ func balancedEncode(target *big.Int, base int, degrees []*big.Int, sumDegrees []
 				}
 			}
 
-			return toBigIntArray(coefficients[:bound+1]), bound + 1
+			return toBigIntArray(coefficients[:bound+1]), bound + 1, nil
 		}
 
 		if degrees[index].Cmp(target) >= 1 {
@@ -265,73 +426,238 @@ func balancedEncode(target *big.Int, base int, degrees []*big.Int, sumDegrees []
 	}
 }
 
-// rationalize float x as a base b encoded polynomial and a scalefactor
-func rationalize(x float64, base int, precision float64) (int64, int) {
+// negateCoefficients negates every coefficient in place
+func negateCoefficients(coefficients []*big.Int) {
+	for _, c := range coefficients {
+		c.Neg(c)
+	}
+}
+
+// PolyEval evaluates the polynomial and scales the result, rounding the
+// exact value given by PolyEvalRat to a big.Float with enough precision
+// to hold its numerator and denominator
+func (p *PolyPlaintext) PolyEval() *big.Float {
+
+	r := p.PolyEvalRat()
+
+	prec := uint(r.Num().BitLen() + r.Denom().BitLen())
+	if prec < 64 {
+		prec = 64
+	}
+
+	return new(big.Float).SetPrec(prec).SetRat(r)
+}
+
+// PolyEvalRat evaluates the polynomial using Horner's method and
+// returns the exact value after applying the scale factor and the denominator
+func (p *PolyPlaintext) PolyEvalRat() *big.Rat {
 
-	factor := math.Floor(x)
+	acc := big.NewInt(0)
+	x := big.NewInt(int64(p.Pk.PolyEncodingParams.PolyBase))
 
-	x = 1.0 + math.Remainder(x, 1.0)
-	if math.Abs(x) > 1.0 {
-		x += 1.0
+	for i := p.Degree - 1; i >= 0; i-- {
+		acc.Mul(acc, x)
+		acc.Add(acc, p.Coefficients[i])
 	}
 
-	if x >= 0.0 {
-		x -= float64(int(x))
-	} else if x <= -0.0 {
-		x += float64(int(x))
+	scale := big.NewInt(0).Exp(
+		big.NewInt(int64(p.Pk.PolyEncodingParams.FPScaleBase)), big.NewInt(int64(p.ScaleFactor)), nil)
+
+	if p.Denominator != nil {
+		scale.Mul(scale, p.Denominator)
 	}
 
-	num := float64(1)
-	pow := float64(1)
+	return new(big.Rat).SetFrac(acc, scale)
+}
 
-	qmin := x - precision
-	qmax := x + precision
+// Neg returns the additive inverse of p
+func (p *PolyPlaintext) Neg() *PolyPlaintext {
 
-	for {
-		// TODO: make more elegant, brute force right now...
-		denom := math.Pow(float64(base), pow)
-		rat := num / denom
-		if rat <= qmax && rat >= qmin {
-
-			// a hacky way to get the min ratio
-			for (int(num) % base) == 0 {
-				num = num / float64(base)
-				pow--
-			}
-			denom := math.Pow(float64(base), pow)
-			return int64(factor*denom + num), int(pow)
+	coefficients := make([]*big.Int, p.Degree)
+	for i := 0; i < p.Degree; i++ {
+		coefficients[i] = new(big.Int).Neg(p.Coefficients[i])
+	}
+
+	return &PolyPlaintext{p.Pk, coefficients, p.Degree, p.ScaleFactor, p.Denominator}
+}
+
+// Add returns p + q with the same coefficients as AddPoly would produce
+// on the encryptions of p and q. Fails if the scale factors cannot be aligned
+func (p *PolyPlaintext) Add(q *PolyPlaintext) (*PolyPlaintext, error) {
+
+	p, q, err := alignPolyPlaintexts(p, q)
+	if err != nil {
+		return nil, err
+	}
+
+	p, q, err = alignPolyPlaintextDenominators(p, q)
+	if err != nil {
+		return nil, err
+	}
+
+	degree := p.Degree
+	if q.Degree > degree {
+		degree = q.Degree
+	}
+
+	coefficients := make([]*big.Int, degree)
+	for i := 0; i < degree; i++ {
+		coefficients[i] = big.NewInt(0)
+
+		if i < p.Degree {
+			coefficients[i].Add(coefficients[i], p.Coefficients[i])
 		}
 
-		if num+1 >= denom {
-			num = float64(1)
-			pow++
+		if i < q.Degree {
+			coefficients[i].Add(coefficients[i], q.Coefficients[i])
 		}
+	}
+
+	return &PolyPlaintext{p.Pk, coefficients, degree, p.ScaleFactor, p.Denominator}, nil
+}
+
+// Sub returns p - q with the same coefficients as SubPoly would produce
+// on the encryptions of p and q. Fails if the scale factors cannot be aligned
+func (p *PolyPlaintext) Sub(q *PolyPlaintext) (*PolyPlaintext, error) {
+	return p.Add(q.Neg())
+}
 
-		num++
+// Mul returns p * q with the same coefficients as MultPoly would produce
+// on the encryptions of p and q
+func (p *PolyPlaintext) Mul(q *PolyPlaintext) *PolyPlaintext {
+
+	degree := p.Degree + q.Degree
+	coefficients := make([]*big.Int, degree)
+	for i := 0; i < degree; i++ {
+		coefficients[i] = big.NewInt(0)
 	}
+
+	for i := 0; i < p.Degree; i++ {
+		for k := 0; k < q.Degree; k++ {
+			term := new(big.Int).Mul(p.Coefficients[i], q.Coefficients[k])
+			coefficients[i+k].Add(coefficients[i+k], term)
+		}
+	}
+
+	return &PolyPlaintext{p.Pk, coefficients, degree, p.ScaleFactor + q.ScaleFactor, mulDenominators(p.Denominator, q.Denominator)}
 }
 
-// PolyEval evaluates a given polynomial using Horner's method
-func (p *PolyPlaintext) PolyEval() *big.Float {
+// Rescale returns p encoded with the larger scaleFactor by multiplying it
+// with FPScaleBase^(scaleFactor - p.ScaleFactor) the same way AddPoly aligns PolyCiphertexts
+func (p *PolyPlaintext) Rescale(scaleFactor int) (*PolyPlaintext, error) {
 
-	acc := big.NewFloat(0.0)
-	x := big.NewFloat(float64(p.Pk.PolyEncodingParams.PolyBase))
+	if scaleFactor < p.ScaleFactor {
+		return nil, errors.New("cannot decrease the scale factor")
+	}
 
-	for i := p.Degree - 1; i >= 0; i-- {
-		acc.Mul(acc, x)
-		acc.Add(acc, new(big.Float).SetInt(p.Coefficients[i]))
+	if scaleFactor == p.ScaleFactor {
+		return p, nil
+	}
+
+	scale, err := p.Pk.NewUnbalancedPlaintext(p.Pk.scaleConstant(scaleFactor - p.ScaleFactor))
+	if err != nil {
+		return nil, err
 	}
 
-	if p.ScaleFactor != 0 {
-		scale := big.NewInt(0).Exp(
-			big.NewInt(int64(p.Pk.PolyEncodingParams.FPScaleBase)), big.NewInt(int64(p.ScaleFactor)), nil)
-		denom := big.NewFloat(0.0).SetInt(scale)
-		res := acc.Quo(acc, denom)
+	product := p.Mul(scale)
+	product.ScaleFactor = scaleFactor
 
-		return res
+	return product, nil
+}
+
+// alignPolyPlaintexts rescales the plaintext with the smaller scale factor
+func alignPolyPlaintexts(p *PolyPlaintext, q *PolyPlaintext) (*PolyPlaintext, *PolyPlaintext, error) {
+
+	var err error
+	if p.ScaleFactor > q.ScaleFactor {
+		q, err = q.Rescale(p.ScaleFactor)
+	} else if q.ScaleFactor > p.ScaleFactor {
+		p, err = p.Rescale(q.ScaleFactor)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p, q, nil
+}
+
+// alignPolyPlaintextDenominators multiplies p and q by integer constants so that
+// both have the least common multiple of their denominators as denominator
+func alignPolyPlaintextDenominators(p *PolyPlaintext, q *PolyPlaintext) (*PolyPlaintext, *PolyPlaintext, error) {
+
+	d1, d2 := denominatorOf(p.Denominator), denominatorOf(q.Denominator)
+	if d1.Cmp(d2) == 0 {
+		return p, q, nil
 	}
 
-	return acc
+	lcm := lcmOf(d1, d2)
+
+	p, err := p.scaleDenominator(new(big.Int).Quo(lcm, d1), lcm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	q, err = q.scaleDenominator(new(big.Int).Quo(lcm, d2), lcm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p, q, nil
+}
+
+// scaleDenominator multiplies p by k the same way MultConstPoly does
+// and sets the denominator to keep the encoded value unchanged
+func (p *PolyPlaintext) scaleDenominator(k *big.Int, denominator *big.Int) (*PolyPlaintext, error) {
+
+	if k.Cmp(big.NewInt(1)) == 0 {
+		return p, nil
+	}
+
+	constant, err := p.Pk.NewUnbalancedPlaintext(new(big.Float).SetInt(k))
+	if err != nil {
+		return nil, err
+	}
+
+	product := p.Mul(constant)
+	product.ScaleFactor = p.ScaleFactor
+	product.Denominator = denominator
+
+	return product, nil
+}
+
+// denominatorOf returns the denominator d or 1 if it is not set
+func denominatorOf(d *big.Int) *big.Int {
+	if d == nil {
+		return big.NewInt(1)
+	}
+
+	return d
+}
+
+// mulDenominators returns the product of two denominators (nil if neither is set)
+func mulDenominators(d1 *big.Int, d2 *big.Int) *big.Int {
+	if d1 == nil && d2 == nil {
+		return nil
+	}
+
+	return new(big.Int).Mul(denominatorOf(d1), denominatorOf(d2))
+}
+
+// lcmOf returns the least common multiple of two positive integers
+func lcmOf(a *big.Int, b *big.Int) *big.Int {
+	gcd := new(big.Int).GCD(nil, nil, a, b)
+	return new(big.Int).Mul(a, new(big.Int).Quo(b, gcd))
+}
+
+// scaleConstant returns FPScaleBase^diff, the constant used to raise
+// the scale factor of an encoding by diff
+func (pk *PublicKey) scaleConstant(diff int) *big.Float {
+
+	scale := new(big.Int).Exp(
+		big.NewInt(int64(pk.PolyEncodingParams.FPScaleBase)), big.NewInt(int64(diff)), nil)
+
+	return new(big.Float).SetInt(scale)
 }
 
 func (p *PolyPlaintext) String() string {
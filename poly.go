@@ -1,11 +1,17 @@
 package bgn
 
 import (
+	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sync"
 )
 
+// ErrPolyOverflow is returned by operations on PolyCiphertexts whose result
+// could have coefficients beyond the message space (and thus fail to decrypt)
+var ErrPolyOverflow = errors.New("coefficients could exceed the message space")
+
 // EncryptPoly encrupts a given plaintext (integer or rational) polynomial
 // encoding under the public key pk
 func (pk *PublicKey) EncryptPoly(pt *PolyPlaintext) *PolyCiphertext {
@@ -25,20 +31,74 @@ func (pk *PublicKey) EncryptPoly(pt *PolyPlaintext) *PolyCiphertext {
 		}
 	}
 
-	return &PolyCiphertext{encryptedCoefficients, pt.Degree, pt.ScaleFactor, false}
+	bound := big.NewInt(0)
+	for _, c := range pt.Coefficients {
+		if c.CmpAbs(bound) > 0 {
+			bound.Abs(c)
+		}
+	}
+
+	return &PolyCiphertext{encryptedCoefficients, pt.Degree, pt.ScaleFactor, false, bound, pt.Denominator}
 }
 
-// DecryptPoly decrupts the PolyCiphertext and returns a PolyPlaintext
-func (sk *SecretKey) DecryptPoly(ct *PolyCiphertext, pk *PublicKey) *PolyPlaintext {
+// PolyDecryptionError reports the coefficient of a PolyCiphertext that failed to decrypt
+type PolyDecryptionError struct {
+	Index int   // index of the first coefficient that failed to decrypt
+	Err   error // decryption error for that coefficient
+}
+
+func (e *PolyDecryptionError) Error() string {
+	return fmt.Sprintf("cannot decrypt coefficient %d: %v", e.Index, e.Err)
+}
+
+func (e *PolyDecryptionError) Unwrap() error {
+	return e.Err
+}
+
+// DecryptPoly decrupts the PolyCiphertext and returns a PolyPlaintext.
+// Fails with a *PolyDecryptionError if a coefficient is outside the message space
+func (sk *SecretKey) DecryptPoly(ct *PolyCiphertext, pk *PublicKey) (*PolyPlaintext, error) {
+	return sk.DecryptPolyWithRecovery(ct, pk, 1)
+}
+
+// DecryptPolyWithRecovery decrypts the PolyCiphertext like DecryptPoly but
+// retries the coefficients that fail to decrypt with a discrete log search
+// window times larger than the message space. This costs O(window * sqrt(MsgSpace))
+// per failing coefficient and uses the existing decryption tables
+func (sk *SecretKey) DecryptPolyWithRecovery(ct *PolyCiphertext, pk *PublicKey, window int) (*PolyPlaintext, error) {
+
+	if window < 1 {
+		return nil, errors.New("recovery window must be at least 1")
+	}
 
 	size := ct.Degree
 	plaintextCoeffs := make([]*big.Int, size)
 
+	var failed []int
 	for i := 0; i < ct.Degree; i++ {
-		plaintextCoeffs[i], _ = sk.Decrypt(ct.Coefficients[i], pk)
+		coeff, err := sk.Decrypt(ct.Coefficients[i], pk)
+		if err != nil {
+			if window == 1 {
+				return nil, &PolyDecryptionError{i, err}
+			}
+
+			failed = append(failed, i)
+			continue
+		}
+
+		plaintextCoeffs[i] = coeff
+	}
+
+	for _, i := range failed {
+		coeff, err := sk.decrypt(ct.Coefficients[i], pk, int64(window), false)
+		if err != nil {
+			return nil, &PolyDecryptionError{i, err}
+		}
+
+		plaintextCoeffs[i] = coeff
 	}
 
-	return &PolyPlaintext{pk, plaintextCoeffs, size, ct.ScaleFactor}
+	return &PolyPlaintext{pk, plaintextCoeffs, size, ct.ScaleFactor, ct.Denominator}, nil
 }
 
 // NegPoly returns the additive inverse of the level1 PolyCiphertext
@@ -51,7 +111,7 @@ func (pk *PublicKey) NegPoly(ct *PolyCiphertext) *PolyCiphertext {
 		result[i] = pk.Sub(pk.encryptZero(), ct.Coefficients[i])
 	}
 
-	return &PolyCiphertext{result, ct.Degree, ct.ScaleFactor, ct.L2}
+	return &PolyCiphertext{result, ct.Degree, ct.ScaleFactor, ct.L2, ct.Bound, ct.Denominator}
 }
 
 // EvalPoly homomorphically evaluates the polynomial on the base
@@ -67,18 +127,116 @@ func (pk *PublicKey) EvalPoly(ct *PolyCiphertext) *Ciphertext {
 	return acc
 }
 
-// MultConstPoly multiplies a PolyCiphertext with a plaintext constant
-func (pk *PublicKey) MultConstPoly(ct *PolyCiphertext, constant *big.Float) *PolyCiphertext {
+// ReducePoly collapses the coefficients of ct at index degree-1 and above
+// into coefficient degree-1 by homomorphically multiplying coefficient i by
+// base^(i-degree+1). The result encrypts the same value with at most degree
+// coefficients, at the cost of a larger top coefficient. ct must be at level1.
+// Fails if the reduced coefficient could overflow the message space
+func (pk *PublicKey) ReducePoly(ct *PolyCiphertext, degree int) (*PolyCiphertext, error) {
+
+	if degree < 1 {
+		return nil, errors.New("target degree must be at least 1")
+	}
+
+	if ct.L2 {
+		return nil, errors.New("only level1 ciphertexts can be reduced")
+	}
+
+	for _, coeff := range ct.Coefficients {
+		if coeff.L2 {
+			return nil, errors.New("only level1 ciphertexts can be reduced")
+		}
+	}
+
+	if ct.Degree <= degree {
+		return ct.Copy(), nil
+	}
+
+	base := big.NewInt(int64(pk.PolyEncodingParams.PolyBase))
+	top := degree - 1
+
+	// the top coefficient sums base^j times the bound for j = 0 ... ct.Degree-degree
+	var bound *big.Int
+	if ct.Bound != nil {
+		weight := big.NewInt(0)
+		power := big.NewInt(1)
+		for i := top; i < ct.Degree; i++ {
+			weight.Add(weight, power)
+			power.Mul(power, base)
+		}
+		bound = weight.Mul(weight, ct.Bound)
+
+		if err := pk.checkPolyBound(bound); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]*Ciphertext, degree)
+	copy(result, ct.Coefficients[:top])
+
+	acc := ct.Coefficients[ct.Degree-1]
+	for i := ct.Degree - 2; i >= top; i-- {
+		acc = pk.MultConst(acc, base)
+		acc = pk.Add(acc, ct.Coefficients[i])
+	}
+	result[top] = acc
+
+	return &PolyCiphertext{result, degree, ct.ScaleFactor, ct.L2, bound, ct.Denominator}, nil
+}
+
+// DivPoly divides a PolyCiphertext by a non-zero plaintext integer.
+// The division is exact: the divisor is accumulated in the denominator
+// of the PolyCiphertext which is applied by PolyEval after decryption
+func (pk *PublicKey) DivPoly(ct *PolyCiphertext, divisor *big.Int) (*PolyCiphertext, error) {
+
+	if divisor.Sign() == 0 {
+		return nil, errors.New("division by zero")
+	}
+
+	result := ct.Copy()
+	if divisor.Sign() < 0 {
+		result = pk.NegPoly(ct)
+	}
+
+	result.Denominator = new(big.Int).Mul(denominatorOf(ct.Denominator), new(big.Int).Abs(divisor))
+
+	return result, nil
+}
+
+// MultConstPoly multiplies a PolyCiphertext with a plaintext constant.
+// The constant provided by the caller is never modified.
+// Fails if the constant cannot be encoded within the degree bound
+// or if the product could overflow the message space
+func (pk *PublicKey) MultConstPoly(ct *PolyCiphertext, constant *big.Float) (*PolyCiphertext, error) {
 
-	isNegative := constant.Cmp(big.NewFloat(0.0)) < 0
+	isNegative := constant.Sign() < 0
 	if isNegative {
-		constant.Mul(constant, big.NewFloat(-1.0))
+		// work on a copy so that the caller's value is left untouched
+		constant = new(big.Float).Abs(constant)
 	}
 
 	pk.mu.Lock()
-	poly := pk.NewUnbalancedPlaintext(constant)
+	poly, err := pk.NewUnbalancedPlaintext(constant)
 	pk.mu.Unlock()
 
+	if err != nil {
+		return nil, err
+	}
+
+	// each coefficient of the product sums at most one term per coefficient of poly
+	var bound *big.Int
+	if ct.Bound != nil {
+		bound = big.NewInt(0)
+		for _, c := range poly.Coefficients {
+			bound.Add(bound, new(big.Int).Abs(c))
+		}
+		bound.Mul(bound, ct.Bound)
+
+		if err := pk.checkPolyBound(bound); err != nil {
+			return nil, err
+		}
+	}
+
 	degree := ct.Degree + poly.Degree
 	result := make([]*Ciphertext, degree)
 
@@ -110,17 +268,34 @@ func (pk *PublicKey) MultConstPoly(ct *PolyCiphertext, constant *big.Float) *Pol
 
 	wg.Wait()
 
-	product := &PolyCiphertext{result, degree, ct.ScaleFactor + poly.ScaleFactor, ct.L2}
+	product := &PolyCiphertext{result, degree, ct.ScaleFactor + poly.ScaleFactor, ct.L2, bound, ct.Denominator}
 
 	if isNegative {
-		return pk.NegPoly(product)
+		return pk.NegPoly(product), nil
 	}
 
-	return product
+	return product, nil
 }
 
-// MultPoly multiplies two L1 PolyCiphertext together
-func (pk *PublicKey) MultPoly(ct1 *PolyCiphertext, ct2 *PolyCiphertext) *PolyCiphertext {
+// MultPoly multiplies two L1 PolyCiphertext together.
+// Fails if the product could overflow the message space
+func (pk *PublicKey) MultPoly(ct1 *PolyCiphertext, ct2 *PolyCiphertext) (*PolyCiphertext, error) {
+
+	// each coefficient of the product sums at most min(deg1, deg2) terms
+	var bound *big.Int
+	if ct1.Bound != nil && ct2.Bound != nil {
+		terms := ct1.Degree
+		if ct2.Degree < terms {
+			terms = ct2.Degree
+		}
+
+		bound = new(big.Int).Mul(ct1.Bound, ct2.Bound)
+		bound.Mul(bound, big.NewInt(int64(terms)))
+
+		if err := pk.checkPolyBound(bound); err != nil {
+			return nil, err
+		}
+	}
 
 	degree := ct1.Degree + ct2.Degree
 	result := make([]*Ciphertext, degree)
@@ -152,38 +327,64 @@ func (pk *PublicKey) MultPoly(ct1 *PolyCiphertext, ct2 *PolyCiphertext) *PolyCip
 	}
 	wg.Wait()
 
-	return &PolyCiphertext{result, degree, ct1.ScaleFactor + ct2.ScaleFactor, true}
+	return &PolyCiphertext{result, degree, ct1.ScaleFactor + ct2.ScaleFactor, true, bound, mulDenominators(ct1.Denominator, ct2.Denominator)}, nil
 }
 
 // MakePolyL2 moves a given PolyCiphertext to the GT field
-func (pk *PublicKey) MakePolyL2(ct *PolyCiphertext) *PolyCiphertext {
+func (pk *PublicKey) MakePolyL2(ct *PolyCiphertext) (*PolyCiphertext, error) {
 
-	one := pk.EncryptPoly(pk.NewPolyPlaintext(big.NewFloat(1.0)))
+	// 1 is always within the degree bound
+	pt, _ := pk.NewPolyPlaintext(big.NewFloat(1.0))
+	one := pk.EncryptPoly(pt)
 	return pk.MultPoly(one, ct)
 }
 
 // SubPoly subtracts PolyCiphertext ct2 from ct1 and returns the result
-func (pk *PublicKey) SubPoly(ct1 *PolyCiphertext, ct2 *PolyCiphertext) *PolyCiphertext {
+func (pk *PublicKey) SubPoly(ct1 *PolyCiphertext, ct2 *PolyCiphertext) (*PolyCiphertext, error) {
 	return pk.AddPoly(ct1, pk.NegPoly(ct2))
 }
 
-// AddPoly adds two PolyCiphertexts together and returns the result
-func (pk *PublicKey) AddPoly(pct1 *PolyCiphertext, pct2 *PolyCiphertext) *PolyCiphertext {
+// AddPoly adds two PolyCiphertexts together and returns the result.
+// Fails if the sum could overflow the message space
+func (pk *PublicKey) AddPoly(pct1 *PolyCiphertext, pct2 *PolyCiphertext) (*PolyCiphertext, error) {
 
 	if pct1.L2 || pct2.L2 {
 
 		if !pct1.L2 {
-			return pk.AddPoly(pk.MakePolyL2(pct1), pct2)
+			l2, err := pk.MakePolyL2(pct1)
+			if err != nil {
+				return nil, err
+			}
+			return pk.AddPoly(l2, pct2)
 		}
 
 		if !pct2.L2 {
-			return pk.AddPoly(pct1, pk.MakePolyL2(pct2))
+			l2, err := pk.MakePolyL2(pct2)
+			if err != nil {
+				return nil, err
+			}
+			return pk.AddPoly(pct1, l2)
 		}
 	}
 
-	ct1 := pct1.Copy()
-	ct2 := pct2.Copy()
-	ct1, ct2 = pk.alignPolyCiphertexts(ct1, ct2, false)
+	ct1, ct2, err := pk.alignPolyCiphertexts(pct1.Copy(), pct2.Copy(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	ct1, ct2, err = pk.alignPolyDenominators(ct1, ct2)
+	if err != nil {
+		return nil, err
+	}
+
+	var bound *big.Int
+	if ct1.Bound != nil && ct2.Bound != nil {
+		bound = new(big.Int).Add(ct1.Bound, ct2.Bound)
+
+		if err := pk.checkPolyBound(bound); err != nil {
+			return nil, err
+		}
+	}
 
 	degree := int(math.Max(float64(ct1.Degree), float64(ct2.Degree)))
 	result := make([]*Ciphertext, degree)
@@ -203,24 +404,175 @@ func (pk *PublicKey) AddPoly(pct1 *PolyCiphertext, pct2 *PolyCiphertext) *PolyCi
 		result[i] = pk.Add(ct1.Coefficients[i], ct2.Coefficients[i])
 	}
 
-	return &PolyCiphertext{result, degree, ct1.ScaleFactor, ct1.L2}
+	return &PolyCiphertext{result, degree, ct1.ScaleFactor, ct1.L2, bound, ct1.Denominator}, nil
+}
+
+// AddPlainPoly adds the plaintext polynomial pt to the PolyCiphertext ct
+// without encrypting pt first. Scale factors are aligned as in AddPoly.
+// Fails if the sum could overflow the message space
+func (pk *PublicKey) AddPlainPoly(ct *PolyCiphertext, pt *PolyPlaintext) (*PolyCiphertext, error) {
+
+	if ct.ScaleFactor > pt.ScaleFactor {
+		var err error
+		if pt, err = pt.Rescale(ct.ScaleFactor); err != nil {
+			return nil, err
+		}
+
+	} else if pt.ScaleFactor > ct.ScaleFactor {
+		scaled, err := pk.MultConstPoly(ct, pk.scaleConstant(pt.ScaleFactor-ct.ScaleFactor))
+		if err != nil {
+			return nil, err
+		}
+		scaled.ScaleFactor = pt.ScaleFactor
+		ct = scaled
+	}
+
+	// align the denominators
+	dct, dpt := denominatorOf(ct.Denominator), denominatorOf(pt.Denominator)
+	if dct.Cmp(dpt) != 0 {
+		lcm := lcmOf(dct, dpt)
+
+		var err error
+		if ct, err = pk.scalePolyDenominator(ct, new(big.Int).Quo(lcm, dct), lcm); err != nil {
+			return nil, err
+		}
+
+		if pt, err = pt.scaleDenominator(new(big.Int).Quo(lcm, dpt), lcm); err != nil {
+			return nil, err
+		}
+	}
+
+	var bound *big.Int
+	if ct.Bound != nil {
+		max := big.NewInt(0)
+		for _, c := range pt.Coefficients {
+			if c.CmpAbs(max) > 0 {
+				max.Abs(c)
+			}
+		}
+		bound = new(big.Int).Add(ct.Bound, max)
+
+		if err := pk.checkPolyBound(bound); err != nil {
+			return nil, err
+		}
+	}
+
+	degree := int(math.Max(float64(ct.Degree), float64(pt.Degree)))
+	result := make([]*Ciphertext, degree)
+
+	for i := degree - 1; i >= 0; i-- {
+
+		if i >= pt.Degree {
+			result[i] = ct.Coefficients[i]
+			continue
+		}
+
+		var coeff *Ciphertext
+		if i < ct.Degree {
+			coeff = ct.Coefficients[i]
+		} else {
+			coeff = pk.encryptZero()
+			if ct.L2 {
+				coeff = pk.makeL2(coeff)
+			}
+		}
+
+		if pt.Coefficients[i].Sign() < 0 {
+			result[i] = pk.Sub(coeff, pk.EncryptDeterministic(new(big.Int).Neg(pt.Coefficients[i])))
+		} else {
+			result[i] = pk.Add(coeff, pk.EncryptDeterministic(pt.Coefficients[i]))
+		}
+	}
+
+	return &PolyCiphertext{result, degree, ct.ScaleFactor, ct.L2, bound, ct.Denominator}, nil
 }
 
 func (pk *PublicKey) alignPolyCiphertexts(
 	ct1 *PolyCiphertext,
 	ct2 *PolyCiphertext,
-	level2 bool) (*PolyCiphertext, *PolyCiphertext) {
+	level2 bool) (*PolyCiphertext, *PolyCiphertext, error) {
 
 	if ct1.ScaleFactor > ct2.ScaleFactor {
 		diff := ct1.ScaleFactor - ct2.ScaleFactor
 
-		ct2 = pk.MultConstPoly(ct2, big.NewFloat(math.Pow(float64(pk.PolyEncodingParams.FPScaleBase), float64(diff))))
+		var err error
+		ct2, err = pk.MultConstPoly(ct2, pk.scaleConstant(diff))
+		if err != nil {
+			return nil, nil, err
+		}
 		ct2.ScaleFactor = ct1.ScaleFactor
 
 	} else if ct2.ScaleFactor > ct1.ScaleFactor {
 		// flip the PolyCiphertexts
-		return pk.alignPolyCiphertexts(ct2, ct1, level2)
+		ct2, ct1, err := pk.alignPolyCiphertexts(ct2, ct1, level2)
+		return ct1, ct2, err
+	}
+
+	return ct1, ct2, nil
+}
+
+// alignPolyDenominators multiplies ct1 and ct2 by integer constants so that
+// both have the least common multiple of their denominators as denominator
+func (pk *PublicKey) alignPolyDenominators(
+	ct1 *PolyCiphertext,
+	ct2 *PolyCiphertext) (*PolyCiphertext, *PolyCiphertext, error) {
+
+	d1, d2 := denominatorOf(ct1.Denominator), denominatorOf(ct2.Denominator)
+	if d1.Cmp(d2) == 0 {
+		return ct1, ct2, nil
+	}
+
+	lcm := lcmOf(d1, d2)
+
+	ct1, err := pk.scalePolyDenominator(ct1, new(big.Int).Quo(lcm, d1), lcm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ct2, err = pk.scalePolyDenominator(ct2, new(big.Int).Quo(lcm, d2), lcm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ct1, ct2, nil
+}
+
+// scalePolyDenominator multiplies ct by k and sets the denominator
+// to keep the encrypted value unchanged
+func (pk *PublicKey) scalePolyDenominator(ct *PolyCiphertext, k *big.Int, denominator *big.Int) (*PolyCiphertext, error) {
+
+	if k.Cmp(big.NewInt(1)) == 0 {
+		return ct, nil
+	}
+
+	product, err := pk.MultConstPoly(ct, new(big.Float).SetInt(k))
+	if err != nil {
+		return nil, err
+	}
+	product.ScaleFactor = ct.ScaleFactor
+	product.Denominator = denominator
+
+	return product, nil
+}
+
+// PolyHeadroom returns how much the magnitude of the coefficients of ct
+// can still grow before they could exceed the message space and fail to decrypt.
+// Returns nil if the coefficient bound of ct is not tracked
+func (pk *PublicKey) PolyHeadroom(ct *PolyCiphertext) *big.Int {
+
+	if ct.Bound == nil {
+		return nil
+	}
+
+	return new(big.Int).Sub(pk.MsgSpace, ct.Bound)
+}
+
+// checkPolyBound fails if a coefficient bound exceeds the message space
+func (pk *PublicKey) checkPolyBound(bound *big.Int) error {
+
+	if bound.Cmp(pk.MsgSpace) > 0 {
+		return ErrPolyOverflow
 	}
 
-	return ct1, ct2
+	return nil
 }
@@ -7,10 +7,21 @@ import (
 	"testing"
 )
 
+func decryptPoly(t *testing.T, sk *SecretKey, pk *PublicKey, ct *PolyCiphertext) *PolyPlaintext {
+	t.Helper()
+
+	pt, err := sk.DecryptPoly(ct, pk)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return pt
+}
+
 func BenchmarkEncryptPoly(b *testing.B) {
 	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
 
-	plaintext := pk.NewPolyPlaintext(big.NewFloat(100.1))
+	plaintext, _ := pk.NewPolyPlaintext(big.NewFloat(100.1))
 	for i := 0; i < b.N; i++ {
 		pk.EncryptPoly(plaintext)
 	}
@@ -25,7 +36,8 @@ func BenchmarkDecryptPoly(b *testing.B) {
 	genGT := pk.Pairing.NewGT().Pair(pk.P, pk.P)
 	genGT.PowBig(genGT, sk.Key)
 
-	zero := pk.EncryptPoly(pk.NewPolyPlaintext(big.NewFloat(0.0)))
+	pt, _ := pk.NewPolyPlaintext(big.NewFloat(0.0))
+	zero := pk.EncryptPoly(pt)
 
 	for i := 0; i < b.N; i++ {
 		sk.DecryptPoly(zero, pk)
@@ -35,7 +47,7 @@ func BenchmarkDecryptPoly(b *testing.B) {
 func BenchmarkAddPoly(b *testing.B) {
 	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
 
-	plaintext := pk.NewPolyPlaintext(big.NewFloat(100.1))
+	plaintext, _ := pk.NewPolyPlaintext(big.NewFloat(100.1))
 	ciphertext := pk.EncryptPoly(plaintext)
 
 	for i := 0; i < b.N; i++ {
@@ -46,7 +58,7 @@ func BenchmarkAddPoly(b *testing.B) {
 func BenchmarkMultConstantPoly(b *testing.B) {
 	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
 
-	plaintext := pk.NewPolyPlaintext(big.NewFloat(100.1))
+	plaintext, _ := pk.NewPolyPlaintext(big.NewFloat(100.1))
 	ciphertext := pk.EncryptPoly(plaintext)
 
 	for i := 0; i < b.N; i++ {
@@ -57,7 +69,7 @@ func BenchmarkMultConstantPoly(b *testing.B) {
 func BenchmarkMultPoly(b *testing.B) {
 	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
 
-	plaintext := pk.NewPolyPlaintext(big.NewFloat(100.1))
+	plaintext, _ := pk.NewPolyPlaintext(big.NewFloat(100.1))
 	ciphertext := pk.EncryptPoly(plaintext)
 
 	for i := 0; i < b.N; i++ {
@@ -69,7 +81,7 @@ func TestEncodeBalancedPoly(t *testing.T) {
 	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
 
 	f1 := big.NewFloat(9.123)
-	p1 := pk.NewPolyPlaintext(f1)
+	p1, _ := pk.NewPolyPlaintext(f1)
 	actual := p1.PolyEval()
 	expected := f1
 	if !reflect.DeepEqual(fmt.Sprintf("%.1f\n", expected), fmt.Sprintf("%.1f\n", actual)) {
@@ -81,7 +93,7 @@ func TestEncodeUnbalancedPoly(t *testing.T) {
 	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
 
 	f1 := big.NewFloat(9.123)
-	p1 := pk.NewUnbalancedPlaintext(f1)
+	p1, _ := pk.NewUnbalancedPlaintext(f1)
 	actual := p1.PolyEval()
 	expected := f1
 	if !reflect.DeepEqual(fmt.Sprintf("%.1f\n", expected), fmt.Sprintf("%.1f\n", actual)) {
@@ -94,28 +106,202 @@ func TestEncodeEncryptDecryptPoly(t *testing.T) {
 	pk.SetupDecryption(sk)
 
 	f1 := big.NewFloat(9.123)
-	p1 := pk.NewPolyPlaintext(f1)
+	p1, _ := pk.NewPolyPlaintext(f1)
 	c1 := pk.EncryptPoly(p1)
-	actual := sk.DecryptPoly(c1, pk).PolyEval()
+	actual := decryptPoly(t, sk, pk, c1).PolyEval()
 	expected := f1
 	if !reflect.DeepEqual(fmt.Sprintf("%.1f\n", expected), fmt.Sprintf("%.1f\n", actual)) {
 		t.Error("Expected: " + expected.String() + " got: " + actual.String())
 	}
 }
 
+func TestEncodeEncryptDecryptPolyNegative(t *testing.T) {
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	values := []float64{-1, -9, -12345, -2.5, -9.123, -0.25}
+
+	for _, v := range values {
+		f := big.NewFloat(v)
+		expected := fmt.Sprintf("%.1f", f)
+
+		p1, _ := pk.NewPolyPlaintext(f)
+		p2, _ := pk.NewUnbalancedPlaintext(f)
+
+		for _, p := range []*PolyPlaintext{p1, p2} {
+			actual := decryptPoly(t, sk, pk, pk.EncryptPoly(p)).PolyEval()
+			if expected != fmt.Sprintf("%.1f", actual) {
+				t.Errorf("Expected: %v got: %v", expected, actual.String())
+			}
+		}
+	}
+}
+
+func TestUnbalancedEncodeNegative(t *testing.T) {
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	coeffs, degree, _ := unbalancedEncode(big.NewInt(-100), pk.PolyEncodingParams.PolyBase, pk.degreeTable, pk.degreeSumTable)
+	p := &PolyPlaintext{pk, coeffs, degree, 0, nil}
+
+	if p.PolyEval().Cmp(big.NewFloat(-100)) != 0 {
+		t.Errorf("Expected: -100 got: %v", p.PolyEval())
+	}
+}
+
+func TestEncodeWithDifferentBases(t *testing.T) {
+	pk1, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk2, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), 2, FPSCALEBASE, FPPREC, DET)
+
+	// encodings under the first key are unaffected by the second key
+	for _, pk := range []*PublicKey{pk1, pk2} {
+		p1, _ := pk.NewPolyPlaintext(big.NewFloat(1000))
+		p2, _ := pk.NewUnbalancedPlaintext(big.NewFloat(1000))
+
+		for _, p := range []*PolyPlaintext{p1, p2} {
+			if p.PolyEval().Cmp(big.NewFloat(1000)) != 0 {
+				t.Errorf("[base=%v] Expected: 1000 got: %v", pk.PolyEncodingParams.PolyBase, p.PolyEval())
+			}
+		}
+	}
+}
+
+func TestEncodeRatExact(t *testing.T) {
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	// 2^70 + 1/3^5 is not representable as a float64 but is exact in base 3
+	expected := new(big.Rat).SetFrac(big.NewInt(1), big.NewInt(243))
+	expected.Add(expected, new(big.Rat).SetInt(new(big.Int).Lsh(big.NewInt(1), 70)))
+
+	p1, _, _ := pk.NewPolyPlaintextFromRat(expected)
+	p2, _, _ := pk.NewUnbalancedPlaintextFromRat(expected)
+
+	for _, p := range []*PolyPlaintext{p1, p2} {
+		actual := decryptPoly(t, sk, pk, pk.EncryptPoly(p)).PolyEvalRat()
+		if actual.Cmp(expected) != 0 {
+			t.Errorf("Expected: %v got: %v", expected, actual)
+		}
+	}
+}
+
+func TestEncodeRatPrecision(t *testing.T) {
+	precision := 1e-30
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, precision, DET)
+
+	value := big.NewRat(-1, 7)
+	pt, roundingErr, _ := pk.NewPolyPlaintextFromRat(value)
+
+	diff := new(big.Rat).Sub(pt.PolyEvalRat(), value)
+	if diff.Cmp(roundingErr) != 0 {
+		t.Errorf("Expected rounding error %v got %v", diff, roundingErr)
+	}
+
+	if diff.Abs(diff).Cmp(new(big.Rat).SetFloat64(precision)) > 0 {
+		t.Errorf("Encoding of %v off by %v", value, diff.FloatString(40))
+	}
+}
+
+func TestEncodeFromString(t *testing.T) {
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, 10, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	// exact in base 10 even though finer than FPPrecision
+	for _, s := range []string{"1234.5678", "-0.000001", "42", "3/4"} {
+		pt, roundingErr, err := pk.NewPolyPlaintextFromString(s)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if roundingErr.Sign() != 0 {
+			t.Errorf("Expected exact encoding of %v, off by %v", s, roundingErr)
+		}
+
+		expected, _ := new(big.Rat).SetString(s)
+		actual := decryptPoly(t, sk, pk, pk.EncryptPoly(pt)).PolyEvalRat()
+		if actual.Cmp(expected) != 0 {
+			t.Errorf("Expected: %v got: %v", expected, actual)
+		}
+	}
+
+	// 1/3 has no finite decimal expansion
+	pt, roundingErr, _ := pk.NewPolyPlaintextFromString("-1/3")
+	if roundingErr.Sign() == 0 {
+		t.Errorf("Expected a rounding error for -1/3")
+	}
+
+	diff := new(big.Rat).Add(pt.PolyEvalRat(), big.NewRat(1, 3))
+	if diff.Cmp(roundingErr) != 0 {
+		t.Errorf("Expected rounding error %v got %v", diff, roundingErr)
+	}
+
+	if _, _, err := pk.NewPolyPlaintextFromString("12.3.4"); err == nil {
+		t.Errorf("Malformed string accepted")
+	}
+}
+
+func TestEncodeDegreeBound(t *testing.T) {
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	params := *pk.PolyEncodingParams
+	params.DegreeBound = 5
+	if err := pk.SetPolyEncodingParams(&params); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// 1 + 3 + ... + 3^4 = 121 is the largest balanced encoding with 5 coefficients
+	pt, err := pk.NewPolyPlaintext(big.NewFloat(121))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if pt.Degree > 5 || pt.PolyEval().Cmp(big.NewFloat(121)) != 0 {
+		t.Errorf("Expected: 121 with at most 5 coefficients got: %v with %v", pt.PolyEval(), pt.Degree)
+	}
+
+	if _, err := pk.NewPolyPlaintext(big.NewFloat(122)); err == nil {
+		t.Errorf("Value beyond the degree bound accepted")
+	}
+
+	if _, err := pk.NewUnbalancedPlaintext(big.NewFloat(-1000)); err == nil {
+		t.Errorf("Value beyond the degree bound accepted")
+	}
+
+	// values with no finite expansion are rounded at the largest scale factor
+	params.FPPrecision = 0
+	pk.SetPolyEncodingParams(&params)
+	pt, roundingErr, err := pk.NewPolyPlaintextFromRat(big.NewRat(1, 7))
+	if err != nil || pt.ScaleFactor != 5 || roundingErr.Sign() == 0 {
+		t.Errorf("Expected a rounded encoding with scale factor 5 got: %v %v", pt, err)
+	}
+
+	params.PolyBase = 1
+	if err := pk.SetPolyEncodingParams(&params); err == nil {
+		t.Errorf("Invalid polynomial base accepted")
+	}
+}
+
+func TestEncodeUnsupportedBase(t *testing.T) {
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), 5, FPSCALEBASE, FPPREC, DET)
+
+	// balanced encodings with coefficients in {-1, 0, 1} cannot represent 2 in base 5
+	if _, err := pk.NewPolyPlaintext(big.NewFloat(2)); err == nil {
+		t.Errorf("Unrepresentable value accepted")
+	}
+}
+
 func TestAddPoly(t *testing.T) {
 	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
 	pk.SetupDecryption(sk)
 
 	f1 := big.NewFloat(0.1)
 	f2 := big.NewFloat(4.2)
-	p1 := pk.NewPolyPlaintext(f1)
-	p2 := pk.NewPolyPlaintext(f2)
+	p1, _ := pk.NewPolyPlaintext(f1)
+	p2, _ := pk.NewPolyPlaintext(f2)
 	c1 := pk.EncryptPoly(p1)
 	c2 := pk.EncryptPoly(p2)
 
-	r1 := pk.AddPoly(c1, c2)
-	actual := sk.DecryptPoly(r1, pk).PolyEval()
+	r1, _ := pk.AddPoly(c1, c2)
+	actual := decryptPoly(t, sk, pk, r1).PolyEval()
 	expected := big.NewFloat(0.0).Add(p1.PolyEval(), p2.PolyEval())
 	if !reflect.DeepEqual(fmt.Sprintf("%.1f\n", expected), fmt.Sprintf("%.1f\n", actual)) {
 		t.Error("Expected: " + expected.String() + " got: " + actual.String())
@@ -128,15 +314,15 @@ func TestAddPolyL2(t *testing.T) {
 
 	f1 := big.NewFloat(50.1)
 	f2 := big.NewFloat(41.2)
-	p1 := pk.NewPolyPlaintext(f1)
-	p2 := pk.NewPolyPlaintext(f2)
+	p1, _ := pk.NewPolyPlaintext(f1)
+	p2, _ := pk.NewPolyPlaintext(f2)
 	c1 := pk.EncryptPoly(p1)
 	c2 := pk.EncryptPoly(p2)
-	c1 = pk.MakePolyL2(c1)
-	c2 = pk.MakePolyL2(c2)
+	c1, _ = pk.MakePolyL2(c1)
+	c2, _ = pk.MakePolyL2(c2)
 
-	r1 := pk.AddPoly(c1, c2)
-	actual := sk.DecryptPoly(r1, pk).PolyEval()
+	r1, _ := pk.AddPoly(c1, c2)
+	actual := decryptPoly(t, sk, pk, r1).PolyEval()
 	expected := big.NewFloat(0.0).Add(p1.PolyEval(), p2.PolyEval())
 	if !reflect.DeepEqual(fmt.Sprintf("%.1f\n", expected), fmt.Sprintf("%.1f\n", actual)) {
 		t.Error("Expected: " + expected.String() + " got: " + actual.String())
@@ -149,20 +335,20 @@ func TestMultConstPoly(t *testing.T) {
 
 	f1 := big.NewFloat(9.13)
 	f2 := big.NewFloat(4.12)
-	p1 := pk.NewPolyPlaintext(f1)
-	p2 := pk.NewPolyPlaintext(f2)
+	p1, _ := pk.NewPolyPlaintext(f1)
+	p2, _ := pk.NewPolyPlaintext(f2)
 	c1 := pk.EncryptPoly(p1)
 
-	r1 := pk.MultConstPoly(c1, f2)
-	actual := sk.DecryptPoly(r1, pk).PolyEval()
+	r1, _ := pk.MultConstPoly(c1, f2)
+	actual := decryptPoly(t, sk, pk, r1).PolyEval()
 	expected := big.NewFloat(0.0).Mul(p1.PolyEval(), p2.PolyEval())
 	if !reflect.DeepEqual(fmt.Sprintf("%.1f\n", expected), fmt.Sprintf("%.1f\n", actual)) {
 		t.Error("[L1] Expected: " + expected.String() + " got: " + actual.String())
 	}
 
-	c1 = pk.MakePolyL2(c1)
-	r1 = pk.MultConstPoly(c1, f2)
-	actual = sk.DecryptPoly(r1, pk).PolyEval()
+	c1, _ = pk.MakePolyL2(c1)
+	r1, _ = pk.MultConstPoly(c1, f2)
+	actual = decryptPoly(t, sk, pk, r1).PolyEval()
 	expected = big.NewFloat(0.0).Mul(p1.PolyEval(), p2.PolyEval())
 	if !reflect.DeepEqual(fmt.Sprintf("%.1f\n", expected), fmt.Sprintf("%.1f\n", actual)) {
 		t.Error("[L2] Expected: " + expected.String() + " got: " + actual.String())
@@ -175,15 +361,346 @@ func TestMultPoly(t *testing.T) {
 
 	f1 := big.NewFloat(1.1)
 	f2 := big.NewFloat(40.2)
-	p1 := pk.NewPolyPlaintext(f1)
-	p2 := pk.NewPolyPlaintext(f2)
+	p1, _ := pk.NewPolyPlaintext(f1)
+	p2, _ := pk.NewPolyPlaintext(f2)
 	c1 := pk.EncryptPoly(p1)
 	c2 := pk.EncryptPoly(p2)
 
-	r1 := pk.MultPoly(c1, c2)
-	actual := sk.DecryptPoly(r1, pk).PolyEval()
+	r1, _ := pk.MultPoly(c1, c2)
+	actual := decryptPoly(t, sk, pk, r1).PolyEval()
 	expected := big.NewFloat(0.0).Mul(p1.PolyEval(), p2.PolyEval())
 	if !reflect.DeepEqual(fmt.Sprintf("%.1f\n", expected), fmt.Sprintf("%.1f\n", actual)) {
 		t.Error("Expected: " + expected.String() + " got: " + actual.String())
 	}
 }
+
+func TestMultConstPolyNegative(t *testing.T) {
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	f1 := big.NewFloat(9.13)
+	f2 := big.NewFloat(-4.12)
+	p1, _ := pk.NewPolyPlaintext(f1)
+	c1 := pk.EncryptPoly(p1)
+
+	r1, _ := pk.MultConstPoly(c1, f2)
+	actual := decryptPoly(t, sk, pk, r1).PolyEval()
+	expected := big.NewFloat(0.0).Mul(p1.PolyEval(), f2)
+	if !reflect.DeepEqual(fmt.Sprintf("%.1f\n", expected), fmt.Sprintf("%.1f\n", actual)) {
+		t.Error("[L1] Expected: " + expected.String() + " got: " + actual.String())
+	}
+
+	c1, _ = pk.MakePolyL2(c1)
+	r1, _ = pk.MultConstPoly(c1, f2)
+	actual = decryptPoly(t, sk, pk, r1).PolyEval()
+	if !reflect.DeepEqual(fmt.Sprintf("%.1f\n", expected), fmt.Sprintf("%.1f\n", actual)) {
+		t.Error("[L2] Expected: " + expected.String() + " got: " + actual.String())
+	}
+
+	if f2.Cmp(big.NewFloat(-4.12)) != 0 {
+		t.Error("Constant was modified: " + f2.String())
+	}
+}
+
+func TestPolyOverflow(t *testing.T) {
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	p1, _ := pk.NewPolyPlaintext(big.NewFloat(13))
+	c1 := pk.EncryptPoly(p1)
+
+	if pk.PolyHeadroom(c1).Cmp(big.NewInt(MSGSPACE-1)) != 0 {
+		t.Fatalf("Expected headroom %v got %v", MSGSPACE-1, pk.PolyHeadroom(c1))
+	}
+
+	// doubling 10 times gives coefficients up to 1024 > MSGSPACE
+	acc := c1
+	for i := 0; i < 9; i++ {
+		next, err := pk.AddPoly(acc, acc)
+		if err != nil {
+			t.Fatalf("Unexpected overflow after %v doublings", i+1)
+		}
+		acc = next
+	}
+
+	if pk.PolyHeadroom(acc).Cmp(big.NewInt(MSGSPACE-512)) != 0 {
+		t.Errorf("Expected headroom %v got %v", MSGSPACE-512, pk.PolyHeadroom(acc))
+	}
+
+	actual := decryptPoly(t, sk, pk, acc).PolyEval()
+	if actual.Cmp(big.NewFloat(13*512)) != 0 {
+		t.Errorf("Expected: %v got: %v", 13*512, actual)
+	}
+
+	if _, err := pk.AddPoly(acc, acc); err != ErrPolyOverflow {
+		t.Errorf("Expected overflow error got %v", err)
+	}
+
+	if _, err := pk.MultConstPoly(acc, big.NewFloat(10)); err != ErrPolyOverflow {
+		t.Errorf("Expected overflow error got %v", err)
+	}
+
+	if _, err := pk.MultPoly(acc, c1); err != ErrPolyOverflow {
+		t.Errorf("Expected overflow error got %v", err)
+	}
+}
+
+func TestDecryptPolyFailure(t *testing.T) {
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	// coefficients 1 and +-2000 where 2000 is beyond the message space
+	coeffs := []*Ciphertext{
+		pk.Encrypt(big.NewInt(1)),
+		pk.Encrypt(big.NewInt(2000)),
+		pk.Encrypt(big.NewInt(-2000)),
+	}
+	ct := NewPolyCiphertext(coeffs, 3, 0, false)
+
+	_, err := sk.DecryptPoly(ct, pk)
+	decErr, ok := err.(*PolyDecryptionError)
+	if !ok || decErr.Index != 1 {
+		t.Fatalf("Expected failure for coefficient 1 got: %v", err)
+	}
+
+	// 1 + 3*2000 - 9*2000
+	pt, err := sk.DecryptPolyWithRecovery(ct, pk, 2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if pt.PolyEval().Cmp(big.NewFloat(1-6*2000)) != 0 {
+		t.Errorf("Expected: %v got: %v", 1-6*2000, pt.PolyEval())
+	}
+
+	// the window is too small to recover the coefficients
+	coeffs[2] = pk.Encrypt(big.NewInt(-5000))
+	_, err = sk.DecryptPolyWithRecovery(ct, pk, 2)
+	decErr, ok = err.(*PolyDecryptionError)
+	if !ok || decErr.Index != 2 {
+		t.Errorf("Expected failure for coefficient 2 got: %v", err)
+	}
+}
+
+func TestReducePoly(t *testing.T) {
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	p1, _ := pk.NewPolyPlaintext(big.NewFloat(-123.75))
+	c1 := pk.EncryptPoly(p1)
+
+	product, err := pk.MultConstPoly(c1, big.NewFloat(17))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	expected := decryptPoly(t, sk, pk, product).PolyEvalRat()
+
+	for degree := product.Degree; degree >= 1; degree-- {
+		reduced, err := pk.ReducePoly(product, degree)
+		if err == ErrPolyOverflow {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if reduced.Degree != degree {
+			t.Errorf("Expected degree %v got %v", degree, reduced.Degree)
+		}
+
+		actual := decryptPoly(t, sk, pk, reduced).PolyEvalRat()
+		if actual.Cmp(expected) != 0 {
+			t.Errorf("Degree %v expected: %v got: %v", degree, expected, actual)
+		}
+	}
+
+	if _, err := pk.ReducePoly(product, 1); err != ErrPolyOverflow {
+		t.Errorf("Expected overflow error got %v", err)
+	}
+
+	if _, err := pk.ReducePoly(product, 0); err == nil {
+		t.Errorf("Expected error for degree 0")
+	}
+
+	l2, err := pk.MultPoly(c1, c1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if _, err := pk.ReducePoly(l2, 1); err == nil {
+		t.Errorf("Expected error for a level2 ciphertext")
+	}
+}
+
+func samePolyCoefficients(p *PolyPlaintext, q *PolyPlaintext) bool {
+	if p.Degree != q.Degree || p.ScaleFactor != q.ScaleFactor {
+		return false
+	}
+
+	for i := 0; i < p.Degree; i++ {
+		if p.Coefficients[i].Cmp(q.Coefficients[i]) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestPolyPlaintextArithmetic(t *testing.T) {
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	p1, _, _ := pk.NewPolyPlaintextFromRat(big.NewRat(25, 9))
+	p2, _, _ := pk.NewPolyPlaintextFromRat(big.NewRat(-13, 1))
+	c1 := pk.EncryptPoly(p1)
+	c2 := pk.EncryptPoly(p2)
+
+	sum, err := p1.Add(p2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	csum, _ := pk.AddPoly(c1, c2)
+	if !samePolyCoefficients(sum, decryptPoly(t, sk, pk, csum)) {
+		t.Errorf("Add does not match AddPoly: %v", sum.Coefficients)
+	}
+	if sum.PolyEvalRat().Cmp(big.NewRat(25-13*9, 9)) != 0 {
+		t.Errorf("Expected: %v got: %v", big.NewRat(25-13*9, 9), sum.PolyEvalRat())
+	}
+
+	diff, err := p2.Sub(p1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	cdiff, _ := pk.SubPoly(c2, c1)
+	if !samePolyCoefficients(diff, decryptPoly(t, sk, pk, cdiff)) {
+		t.Errorf("Sub does not match SubPoly: %v", diff.Coefficients)
+	}
+
+	product := p1.Mul(p2)
+	cproduct, _ := pk.MultPoly(c1, c2)
+	if !samePolyCoefficients(product, decryptPoly(t, sk, pk, cproduct)) {
+		t.Errorf("Mul does not match MultPoly: %v", product.Coefficients)
+	}
+	if product.PolyEvalRat().Cmp(big.NewRat(-25*13, 9)) != 0 {
+		t.Errorf("Expected: %v got: %v", big.NewRat(-25*13, 9), product.PolyEvalRat())
+	}
+
+	if p1.Neg().PolyEvalRat().Cmp(big.NewRat(-25, 9)) != 0 {
+		t.Errorf("Expected: %v got: %v", big.NewRat(-25, 9), p1.Neg().PolyEvalRat())
+	}
+
+	if _, err := p1.Rescale(p1.ScaleFactor - 1); err == nil {
+		t.Errorf("Expected error when decreasing the scale factor")
+	}
+}
+
+func TestAddPlainPoly(t *testing.T) {
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	p1, _, _ := pk.NewPolyPlaintextFromRat(big.NewRat(-40, 1))
+	p2, _, _ := pk.NewPolyPlaintextFromRat(big.NewRat(7, 27))
+	c1 := pk.EncryptPoly(p1)
+
+	expected, _ := p1.Add(p2)
+
+	// p2 has the larger scale factor so c1 must be rescaled
+	sum, err := pk.AddPlainPoly(c1, p2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !samePolyCoefficients(expected, decryptPoly(t, sk, pk, sum)) {
+		t.Errorf("AddPlainPoly does not match Add: %v", expected.Coefficients)
+	}
+
+	// and the other way around at level 2
+	l2, _ := pk.MakePolyL2(pk.EncryptPoly(p2))
+	sum, err = pk.AddPlainPoly(l2, p1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !sum.L2 {
+		t.Errorf("Expected a level2 PolyCiphertext")
+	}
+
+	actual := decryptPoly(t, sk, pk, sum).PolyEvalRat()
+	if actual.Cmp(expected.PolyEvalRat()) != 0 {
+		t.Errorf("Expected: %v got: %v", expected.PolyEvalRat(), actual)
+	}
+}
+
+func TestDivPoly(t *testing.T) {
+	pk, sk, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	pk.SetupDecryption(sk)
+
+	p1, _, _ := pk.NewPolyPlaintextFromRat(big.NewRat(10, 1))
+	p2, _, _ := pk.NewPolyPlaintextFromRat(big.NewRat(-5, 3))
+	c1 := pk.EncryptPoly(p1)
+	c2 := pk.EncryptPoly(p2)
+
+	q1, err := pk.DivPoly(c1, big.NewInt(7))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	actual := decryptPoly(t, sk, pk, q1).PolyEvalRat()
+	if actual.Cmp(big.NewRat(10, 7)) != 0 {
+		t.Errorf("Expected: %v got: %v", big.NewRat(10, 7), actual)
+	}
+
+	q2, err := pk.DivPoly(c2, big.NewInt(-4))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	actual = decryptPoly(t, sk, pk, q2).PolyEvalRat()
+	if actual.Cmp(big.NewRat(5, 12)) != 0 {
+		t.Errorf("Expected: %v got: %v", big.NewRat(5, 12), actual)
+	}
+
+	// 10/7 + 5/12 over the common denominator 84
+	sum, err := pk.AddPoly(q1, q2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	actual = decryptPoly(t, sk, pk, sum).PolyEvalRat()
+	if actual.Cmp(big.NewRat(10*12+5*7, 84)) != 0 {
+		t.Errorf("Expected: %v got: %v", big.NewRat(10*12+5*7, 84), actual)
+	}
+
+	product, err := pk.MultPoly(q1, q2)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	actual = decryptPoly(t, sk, pk, product).PolyEvalRat()
+	if actual.Cmp(big.NewRat(50, 84)) != 0 {
+		t.Errorf("Expected: %v got: %v", big.NewRat(50, 84), actual)
+	}
+
+	// plaintext arithmetic and AddPlainPoly apply the same denominators
+	pt1 := decryptPoly(t, sk, pk, q1)
+	expected, err := pt1.Add(decryptPoly(t, sk, pk, q2))
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !samePolyCoefficients(expected, decryptPoly(t, sk, pk, sum)) {
+		t.Errorf("Add does not match AddPoly: %v", expected.Coefficients)
+	}
+
+	plainSum, err := pk.AddPlainPoly(q2, pt1)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	actual = decryptPoly(t, sk, pk, plainSum).PolyEvalRat()
+	if actual.Cmp(expected.PolyEvalRat()) != 0 {
+		t.Errorf("Expected: %v got: %v", expected.PolyEvalRat(), actual)
+	}
+
+	if _, err := pk.DivPoly(c1, big.NewInt(0)); err == nil {
+		t.Errorf("Expected error when dividing by zero")
+	}
+}
@@ -0,0 +1,169 @@
+package bgn
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Quadratic is a symbolic quadratic polynomial in NumVars variables
+// of the form sum_{i,j} a_ij x_i x_j + sum_i b_i x_i + c
+type Quadratic struct {
+	NumVars  int
+	Quad     [][]*big.Int // Quad[i][j] is the coefficient of x_i x_j
+	Linear   []*big.Int   // Linear[i] is the coefficient of x_i
+	Constant *big.Int     // constant term
+}
+
+// Literal is a (possibly negated) boolean variable of a 2-DNF formula
+type Literal struct {
+	Var     int  // index of the variable
+	Negated bool // whether the literal is NOT x_Var
+}
+
+// Clause is a conjunction of two literals
+type Clause [2]Literal
+
+// NewQuadratic creates the zero polynomial in numVars variables
+func NewQuadratic(numVars int) *Quadratic {
+
+	quad := make([][]*big.Int, numVars)
+	linear := make([]*big.Int, numVars)
+
+	for i := 0; i < numVars; i++ {
+		quad[i] = make([]*big.Int, numVars)
+		for j := 0; j < numVars; j++ {
+			quad[i][j] = big.NewInt(0)
+		}
+		linear[i] = big.NewInt(0)
+	}
+
+	return &Quadratic{numVars, quad, linear, big.NewInt(0)}
+}
+
+// AddQuadraticTerm adds coeff * x_i * x_j to the polynomial
+func (q *Quadratic) AddQuadraticTerm(i, j int, coeff *big.Int) *Quadratic {
+	q.Quad[i][j].Add(q.Quad[i][j], coeff)
+	return q
+}
+
+// AddLinearTerm adds coeff * x_i to the polynomial
+func (q *Quadratic) AddLinearTerm(i int, coeff *big.Int) *Quadratic {
+	q.Linear[i].Add(q.Linear[i], coeff)
+	return q
+}
+
+// AddConstant adds coeff to the constant term of the polynomial
+func (q *Quadratic) AddConstant(coeff *big.Int) *Quadratic {
+	q.Constant.Add(q.Constant, coeff)
+	return q
+}
+
+// Eval evaluates the polynomial on plaintext values
+func (q *Quadratic) Eval(x []*big.Int) *big.Int {
+
+	res := new(big.Int).Set(q.Constant)
+	for i := 0; i < q.NumVars; i++ {
+		res.Add(res, new(big.Int).Mul(q.Linear[i], x[i]))
+		for j := 0; j < q.NumVars; j++ {
+			term := new(big.Int).Mul(q.Quad[i][j], x[i])
+			res.Add(res, term.Mul(term, x[j]))
+		}
+	}
+
+	return res
+}
+
+// EvalQuadratic homomorphically evaluates the quadratic polynomial q
+// on the level1 ciphertexts x and returns the level2 result.
+// Each row of the quadratic form is first collapsed into a level1
+// linear combination so that only one pairing per variable is needed
+func (pk *PublicKey) EvalQuadratic(q *Quadratic, x []*Ciphertext) (*Ciphertext, error) {
+
+	if len(x) != q.NumVars {
+		return nil, errors.New("number of ciphertexts does not match number of variables")
+	}
+
+	for _, ct := range x {
+		if ct.L2 {
+			return nil, errors.New("cannot evaluate a quadratic on level2 ciphertexts")
+		}
+	}
+
+	// sum_i x_i * (sum_j a_ij x_j)
+	left := make([]*Ciphertext, 0)
+	right := make([]*Ciphertext, 0)
+	for i := 0; i < q.NumVars; i++ {
+		if isZeroRow(q.Quad[i]) {
+			continue
+		}
+		left = append(left, x[i])
+		right = append(right, pk.InnerProductPlain(x, q.Quad[i]))
+	}
+
+	res := pk.InnerProduct(left, right)
+
+	// sum_i b_i x_i + c
+	linear := pk.InnerProductPlain(x, q.Linear)
+	linear = pk.Add(linear, pk.EncryptDeterministic(new(big.Int).Mod(q.Constant, pk.N)))
+
+	return pk.Add(res, linear), nil
+}
+
+// Eval2DNF homomorphically evaluates the 2-DNF formula given by the
+// disjunction of clauses on the level1 encrypted bits x.
+// The resulting level2 ciphertext encrypts the number of satisfied clauses,
+// which is zero if and only if the formula is false
+func (pk *PublicKey) Eval2DNF(clauses []Clause, x []*Ciphertext) (*Ciphertext, error) {
+
+	q, err := New2DNFQuadratic(clauses, len(x))
+	if err != nil {
+		return nil, err
+	}
+
+	return pk.EvalQuadratic(q, x)
+}
+
+// New2DNFQuadratic arithmetizes a 2-DNF formula in numVars variables.
+// Each clause (l1 AND l2) becomes the product of its literals, where
+// NOT x is replaced by 1 - x, and the clauses are summed together
+func New2DNFQuadratic(clauses []Clause, numVars int) (*Quadratic, error) {
+
+	q := NewQuadratic(numVars)
+
+	for _, clause := range clauses {
+
+		// each literal is a0 + a1*x
+		a0, a1 := literalCoefficients(clause[0])
+		b0, b1 := literalCoefficients(clause[1])
+		i, j := clause[0].Var, clause[1].Var
+
+		if i < 0 || i >= numVars || j < 0 || j >= numVars {
+			return nil, errors.New("clause references a variable out of range")
+		}
+
+		q.AddConstant(big.NewInt(a0 * b0))
+		q.AddLinearTerm(j, big.NewInt(a0*b1))
+		q.AddLinearTerm(i, big.NewInt(a1*b0))
+		q.AddQuadraticTerm(i, j, big.NewInt(a1*b1))
+	}
+
+	return q, nil
+}
+
+func literalCoefficients(l Literal) (int64, int64) {
+	if l.Negated {
+		return 1, -1
+	}
+
+	return 0, 1
+}
+
+func isZeroRow(row []*big.Int) bool {
+	for _, v := range row {
+		if v.Sign() != 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,103 @@
+package bgn
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestEvalQuadratic(t *testing.T) {
+
+	pk, sk, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	pk.SetupDecryption(sk)
+
+	// 3*x0*x1 - 2*x1^2 + x2*x0 + 5*x2 - 1*x0 + 4
+	q := NewQuadratic(3)
+	q.AddQuadraticTerm(0, 1, big.NewInt(3))
+	q.AddQuadraticTerm(1, 1, big.NewInt(-2))
+	q.AddQuadraticTerm(2, 0, big.NewInt(1))
+	q.AddLinearTerm(2, big.NewInt(5))
+	q.AddLinearTerm(0, big.NewInt(-1))
+	q.AddConstant(big.NewInt(4))
+
+	values := []*big.Int{big.NewInt(2), big.NewInt(3), big.NewInt(-1)}
+	x := make([]*Ciphertext, len(values))
+	for i, v := range values {
+		x[i] = pk.Encrypt(v)
+	}
+
+	res, err := pk.EvalQuadratic(q, x)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	actual, err := sk.Decrypt(res, pk)
+	if err != nil {
+		t.Fatalf("Error when decrypting %v\n", err.Error())
+	}
+
+	expected := q.Eval(values)
+	if actual.Cmp(expected) != 0 {
+		t.Errorf("Expected: %v got: %v\n", expected, actual)
+	}
+}
+
+func TestEval2DNF(t *testing.T) {
+
+	pk, sk, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	pk.SetupDecryption(sk)
+
+	// (x0 AND NOT x1) OR (x1 AND x2)
+	clauses := []Clause{
+		{Literal{0, false}, Literal{1, true}},
+		{Literal{1, false}, Literal{2, false}},
+	}
+
+	for assignment := 0; assignment < 8; assignment++ {
+
+		bits := []bool{assignment&1 != 0, assignment&2 != 0, assignment&4 != 0}
+		x := make([]*Ciphertext, len(bits))
+		for i, b := range bits {
+			if b {
+				x[i] = pk.Encrypt(big.NewInt(1))
+			} else {
+				x[i] = pk.Encrypt(big.NewInt(0))
+			}
+		}
+
+		res, err := pk.Eval2DNF(clauses, x)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		actual, err := sk.Decrypt(res, pk)
+		if err != nil {
+			t.Fatalf("Error when decrypting %v\n", err.Error())
+		}
+
+		expected := (bits[0] && !bits[1]) || (bits[1] && bits[2])
+		if expected != (actual.Sign() != 0) {
+			t.Errorf("Assignment %v: expected %v got %v\n", bits, expected, actual)
+		}
+	}
+}
+
+func TestEval2DNFOutOfRange(t *testing.T) {
+
+	pk, _, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	clauses := []Clause{{Literal{0, false}, Literal{3, false}}}
+	x := []*Ciphertext{pk.Encrypt(big.NewInt(1))}
+
+	if _, err := pk.Eval2DNF(clauses, x); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,246 @@
+package bgn
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+)
+
+// RangeProof is a proof that a level1 ciphertext encrypts a value
+// in [0, 2^Bits). The value is decomposed into encrypted bits, each with
+// a BitProof, whose weighted product is the original ciphertext
+type RangeProof struct {
+	Bits      int
+	BitCts    []*Ciphertext
+	BitProofs []*BitProof
+
+	pk *PublicKey // key used to decode the proof in UnmarshalJSON
+}
+
+type rangeProofWrapper struct {
+	Bits      int
+	BitCts    [][]byte
+	BitProofs []*bitProofWrapper
+}
+
+// NewRangeProof generates a proof that EncryptWithRandomness(v, r)
+// encrypts a value in [0, 2^bits). The range must fit in the message space,
+// i.e. 2^bits <= MsgSpace
+func (pk *PublicKey) NewRangeProof(v *big.Int, r *big.Int, bits int) (*RangeProof, error) {
+
+	if bits < 1 {
+		return nil, errors.New("range must contain at least one bit")
+	}
+
+	if bits > pk.maxRangeBits() {
+		return nil, errors.New("range does not fit in the message space")
+	}
+
+	if v.Sign() < 0 || v.BitLen() > bits {
+		return nil, errors.New("value is out of range")
+	}
+
+	// bit randomness must satisfy sum_i 2^i r_i = r mod N
+	rs := make([]*big.Int, bits)
+	acc := big.NewInt(0)
+	for i := 0; i < bits-1; i++ {
+		rs[i] = newCryptoRandom(pk.N)
+		acc.Add(acc, new(big.Int).Lsh(rs[i], uint(i)))
+	}
+
+	last := new(big.Int).Sub(r, acc)
+	inv := new(big.Int).ModInverse(new(big.Int).Lsh(big.NewInt(1), uint(bits-1)), pk.N)
+	if inv == nil {
+		return nil, errors.New("2 is not invertible mod N")
+	}
+	last.Mul(last, inv)
+	rs[bits-1] = last.Mod(last, pk.N)
+
+	proof := &RangeProof{bits, make([]*Ciphertext, bits), make([]*BitProof, bits), pk}
+	for i := 0; i < bits; i++ {
+		b := big.NewInt(int64(v.Bit(i)))
+		proof.BitCts[i] = pk.EncryptWithRandomness(b, rs[i])
+		proof.BitProofs[i] = pk.NewBitProof(b, rs[i])
+	}
+
+	return proof, nil
+}
+
+// VerifyRangeProof outputs true if proof shows that
+// the level1 ciphertext ct encrypts a value in [0, 2^bits).
+// Ranges that don't fit in the message space are always rejected
+func (pk *PublicKey) VerifyRangeProof(ct *Ciphertext, bits int, proof *RangeProof) bool {
+
+	if !pk.checkRangeProofShape(ct, bits, proof) {
+		return false
+	}
+
+	for i := 0; i < proof.Bits; i++ {
+		if !pk.VerifyBitProof(proof.BitCts[i], proof.BitProofs[i]) {
+			return false
+		}
+	}
+
+	return pk.recomposeBits(proof.BitCts).C.Equals(ct.C)
+}
+
+// BatchVerifyRangeProofs checks many range proofs at once by combining
+// all the Schnorr and recomposition equations into a single randomized check.
+// Outputs true only if every proof is valid
+func (pk *PublicKey) BatchVerifyRangeProofs(cts []*Ciphertext, bits int, proofs []*RangeProof) bool {
+
+	if len(cts) != len(proofs) {
+		return false
+	}
+
+	bv := pk.newBatchVerifier(pk.Q)
+
+	for j, proof := range proofs {
+		if !pk.checkRangeProofShape(cts[j], bits, proof) {
+			return false
+		}
+
+		// prod_i ct_i^(2^i) / ct = 1
+		terms := make([]batchTerm, 0, proof.Bits+1)
+		for i, bitCt := range proof.BitCts {
+			if !pk.addBitProof(bv, bitCt, proof.BitProofs[i]) {
+				return false
+			}
+			terms = append(terms, batchTerm{bitCt.C, new(big.Int).Lsh(big.NewInt(1), uint(i))})
+		}
+		terms = append(terms, batchTerm{cts[j].C, big.NewInt(-1)})
+		bv.addEquation(terms...)
+	}
+
+	return bv.verify()
+}
+
+// addBitProof checks the challenges of the bit proof and adds
+// its Schnorr equations to the batch
+func (pk *PublicKey) addBitProof(bv *batchVerifier, ct *Ciphertext, proof *BitProof) bool {
+
+	if !pk.checkBitProofChallenges(ct, proof) {
+		return false
+	}
+
+	y0, y1 := pk.bitProofStatements(ct)
+	bv.addSchnorr(y0, proof.A0, proof.C0, proof.Z0)
+	bv.addSchnorr(y1, proof.A1, proof.C1, proof.Z1)
+
+	return true
+}
+
+// maxRangeBits is the largest number of bits such that 2^bits <= MsgSpace.
+// The bits are only recomposed modulo the group order, and the key primes are
+// at least MsgSpace, so larger ranges could wrap around and prove out of range values
+func (pk *PublicKey) maxRangeBits() int {
+	return pk.MsgSpace.BitLen() - 1
+}
+
+func (pk *PublicKey) checkRangeProofShape(ct *Ciphertext, bits int, proof *RangeProof) bool {
+	return proof != nil && !ct.L2 && bits <= pk.maxRangeBits() && proof.Bits >= 1 && proof.Bits <= bits &&
+		len(proof.BitCts) == proof.Bits && len(proof.BitProofs) == proof.Bits
+}
+
+// recomposeBits computes prod_i ct_i^(2^i)
+func (pk *PublicKey) recomposeBits(bitCts []*Ciphertext) *Ciphertext {
+
+	acc := pk.encryptZero()
+	for i := len(bitCts) - 1; i >= 0; i-- {
+		acc.C.Mul(acc.C, acc.C)
+		acc.C.Mul(acc.C, bitCts[i].C)
+	}
+
+	return acc
+}
+
+func (proof *RangeProof) wrapper() *rangeProofWrapper {
+
+	w := &rangeProofWrapper{Bits: proof.Bits}
+	for i := 0; i < proof.Bits; i++ {
+		w.BitCts = append(w.BitCts, proof.BitCts[i].C.Bytes())
+		w.BitProofs = append(w.BitProofs, proof.BitProofs[i].wrapper())
+	}
+
+	return w
+}
+
+// Bytes returns the marshalled bytes of the range proof
+func (proof *RangeProof) Bytes() ([]byte, error) {
+	return gobEncode(proof.wrapper())
+}
+
+// MarshalJSON returns the JSON encoding of the range proof
+func (proof *RangeProof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(proof.wrapper())
+}
+
+// NewEmptyRangeProof returns an empty range proof bound to the public key
+// which can be used as the target of json.Unmarshal
+func (pk *PublicKey) NewEmptyRangeProof() *RangeProof {
+	return &RangeProof{pk: pk}
+}
+
+// UnmarshalJSON decodes the JSON encoding of a range proof. The proof must be
+// bound to a public key (see NewEmptyRangeProof) to ensure the correct pairing is used
+func (proof *RangeProof) UnmarshalJSON(data []byte) error {
+
+	if proof.pk == nil {
+		return errors.New("range proof is not bound to a public key")
+	}
+
+	res, err := proof.pk.decodeRangeProof(data, jsonDecode)
+	if err != nil {
+		return err
+	}
+
+	*proof = *res
+	return nil
+}
+
+// NewRangeProofFromBytes generates a range proof from a marshalled range proof.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewRangeProofFromBytes(data []byte) (*RangeProof, error) {
+	return pk.decodeRangeProof(data, gobDecode)
+}
+
+// NewRangeProofFromJSON generates a range proof from its JSON encoding.
+// Requires the public key in order to ensure the correct pairing is used
+func (pk *PublicKey) NewRangeProofFromJSON(data []byte) (*RangeProof, error) {
+	return pk.decodeRangeProof(data, jsonDecode)
+}
+
+func (pk *PublicKey) decodeRangeProof(data []byte, decode decodeFunc) (*RangeProof, error) {
+
+	w := rangeProofWrapper{}
+	if err := decode(data, &w); err != nil {
+		return nil, err
+	}
+
+	n := w.Bits
+	if n < 1 || len(w.BitCts) != n || len(w.BitProofs) != n {
+		return nil, errors.New("malformed range proof")
+	}
+
+	proof := &RangeProof{n, make([]*Ciphertext, n), make([]*BitProof, n), pk}
+	for i := 0; i < n; i++ {
+		if w.BitCts[i] == nil || w.BitProofs[i] == nil {
+			return nil, errMalformedProof
+		}
+
+		c, err := pk.elementFromBytes(w.BitCts[i], false)
+		if err != nil {
+			return nil, err
+		}
+
+		bp, err := pk.bitProofFromWrapper(w.BitProofs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		proof.BitCts[i] = NewCiphertext(c, false)
+		proof.BitProofs[i] = bp
+	}
+
+	return proof, nil
+}
@@ -0,0 +1,201 @@
+package bgn
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestRangeProofValid(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	for _, v := range []int64{0, 1, 37, 255} {
+		r := newCryptoRandom(pk.N)
+		ct := pk.EncryptWithRandomness(big.NewInt(v), r)
+
+		proof, err := pk.NewRangeProof(big.NewInt(v), r, 8)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if !pk.VerifyRangeProof(ct, 8, proof) {
+			t.Errorf("Valid proof for %d rejected", v)
+		}
+	}
+}
+
+func TestRangeProofBad(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	r := newCryptoRandom(pk.N)
+
+	if _, err := pk.NewRangeProof(big.NewInt(256), r, 8); err == nil {
+		t.Errorf("Proof generated for an out of range value")
+	}
+
+	proof, _ := pk.NewRangeProof(big.NewInt(200), r, 8)
+
+	// proof does not transfer to another value
+	ct := pk.EncryptWithRandomness(big.NewInt(201), r)
+	if pk.VerifyRangeProof(ct, 8, proof) {
+		t.Errorf("Proof accepted for a different value")
+	}
+
+	// proof for a larger range is rejected
+	ct = pk.EncryptWithRandomness(big.NewInt(200), r)
+	if pk.VerifyRangeProof(ct, 7, proof) {
+		t.Errorf("Proof accepted for a smaller range")
+	}
+
+	// a bit ciphertext encrypting 2 breaks the bit proof
+	proof.BitCts[0] = pk.EncryptWithRandomness(big.NewInt(2), r)
+	if pk.VerifyRangeProof(ct, 8, proof) {
+		t.Errorf("Proof with a non-bit ciphertext accepted")
+	}
+}
+
+func TestRangeProofTooManyBits(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	// 2^10 > MSGSPACE
+	r := newCryptoRandom(pk.N)
+	if _, err := pk.NewRangeProof(big.NewInt(1), r, 10); err == nil {
+		t.Errorf("Proof generated for a range larger than the message space")
+	}
+
+	proof, err := pk.NewRangeProof(big.NewInt(1), r, 9)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	ct := pk.EncryptWithRandomness(big.NewInt(1), r)
+	if pk.VerifyRangeProof(ct, 10, proof) {
+		t.Errorf("Proof accepted for a range larger than the message space")
+	}
+
+	if pk.BatchVerifyRangeProofs([]*Ciphertext{ct}, 10, []*RangeProof{proof}) {
+		t.Errorf("Batch accepted for a range larger than the message space")
+	}
+}
+
+func TestRangeProofToFromBytes(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	r := newCryptoRandom(pk.N)
+	ct := pk.EncryptWithRandomness(big.NewInt(99), r)
+	proof, _ := pk.NewRangeProof(big.NewInt(99), r, 9)
+
+	bytes, err := proof.Bytes()
+	if err != nil {
+		t.Fatalf("Error when encoding proof to bytes %v\n", err.Error())
+	}
+
+	recovered, err := pk.NewRangeProofFromBytes(bytes)
+	if err != nil {
+		t.Fatalf("Error when recovering proof from bytes %v\n", err.Error())
+	}
+
+	if !pk.VerifyRangeProof(ct, 9, recovered) {
+		t.Errorf("Recovered proof rejected")
+	}
+
+	data, err := proof.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Error when encoding proof to JSON %v\n", err.Error())
+	}
+
+	recovered, err = pk.NewRangeProofFromJSON(data)
+	if err != nil {
+		t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+	}
+
+	if !pk.VerifyRangeProof(ct, 9, recovered) {
+		t.Errorf("Recovered proof rejected")
+	}
+
+	// the proof can be embedded in other JSON documents
+	msg := struct{ Proof *RangeProof }{proof}
+	data, err = json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Error when encoding proof to JSON %v\n", err.Error())
+	}
+
+	msg.Proof = pk.NewEmptyRangeProof()
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("Error when recovering proof from JSON %v\n", err.Error())
+	}
+
+	if !pk.VerifyRangeProof(ct, 9, msg.Proof) {
+		t.Errorf("Recovered proof rejected")
+	}
+
+	if err := json.Unmarshal(data, &struct{ Proof *RangeProof }{}); err == nil {
+		t.Errorf("Proof decoded without a public key")
+	}
+}
+
+func TestRangeProofFromJSONMalformed(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	r := newCryptoRandom(pk.N)
+	proof, _ := pk.NewRangeProof(big.NewInt(1), r, 2)
+
+	w := proof.wrapper()
+	w.BitProofs[1] = nil
+	data, _ := json.Marshal(w)
+	if _, err := pk.NewRangeProofFromJSON(data); err == nil {
+		t.Errorf("Proof with a null bit proof accepted")
+	}
+
+	w = proof.wrapper()
+	w.BitCts[1] = nil
+	data, _ = json.Marshal(w)
+	if _, err := pk.NewRangeProofFromJSON(data); err == nil {
+		t.Errorf("Proof with a null bit ciphertext accepted")
+	}
+}
+
+func TestBatchVerifyRangeProofs(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	cts := make([]*Ciphertext, 5)
+	proofs := make([]*RangeProof, 5)
+	for i := range cts {
+		v := big.NewInt(int64(10 * i))
+		r := newCryptoRandom(pk.N)
+		cts[i] = pk.EncryptWithRandomness(v, r)
+		proofs[i], _ = pk.NewRangeProof(v, r, 6)
+	}
+
+	if !pk.BatchVerifyRangeProofs(cts, 6, proofs) {
+		t.Fatalf("Valid batch rejected")
+	}
+
+	// swap in a ciphertext the proof does not match
+	cts[3] = pk.Encrypt(big.NewInt(30))
+	if pk.BatchVerifyRangeProofs(cts, 6, proofs) {
+		t.Errorf("Invalid batch accepted")
+	}
+}
+
+func BenchmarkRangeProofVerify(b *testing.B) {
+	pk, _, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		panic(err)
+	}
+
+	r := newCryptoRandom(pk.N)
+	ct := pk.EncryptWithRandomness(big.NewInt(500), r)
+	proof, _ := pk.NewRangeProof(big.NewInt(500), r, 9)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pk.VerifyRangeProof(ct, 9, proof)
+	}
+}
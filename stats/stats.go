@@ -0,0 +1,299 @@
+package stats
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/sachaservan/bgn"
+)
+
+// Summary holds the decrypted summary statistics of a stream of
+// (possibly multivariate) observations
+type Summary struct {
+	Count      int
+	Sum        []*big.Float   // Sum[i] is the sum of the i-th variable
+	SumProduct [][]*big.Float // SumProduct[i][j] is the sum of products of variables i and j
+	Mean       []*big.Float   // Mean[i] is the mean of the i-th variable
+	Covariance [][]*big.Float // population covariance matrix (variances on the diagonal)
+}
+
+// Accumulator aggregates a stream of encrypted integer observations
+// using Add for sums and Mult for sums of products
+type Accumulator struct {
+	Pk         *bgn.PublicKey
+	Dims       int                 // number of variables per observation
+	Count      int                 // number of observations accumulated
+	Sum        []*bgn.Ciphertext   // level1 sums
+	SumProduct [][]*bgn.Ciphertext // level2 sums of products (upper triangle)
+
+	mu sync.Mutex
+}
+
+// PolyAccumulator aggregates a stream of encrypted fixed-point observations
+// using AddPoly for sums and MultPoly for sums of products
+type PolyAccumulator struct {
+	Pk         *bgn.PublicKey
+	Dims       int                     // number of variables per observation
+	Count      int                     // number of observations accumulated
+	Sum        []*bgn.PolyCiphertext   // level1 sums
+	SumProduct [][]*bgn.PolyCiphertext // level2 sums of products (upper triangle)
+
+	mu sync.Mutex
+}
+
+// NewAccumulator creates an accumulator for observations of dims variables
+func NewAccumulator(pk *bgn.PublicKey, dims int) *Accumulator {
+	return &Accumulator{
+		Pk:         pk,
+		Dims:       dims,
+		Sum:        make([]*bgn.Ciphertext, dims),
+		SumProduct: newTriangle(dims),
+	}
+}
+
+// NewPolyAccumulator creates an accumulator for fixed-point observations of dims variables
+func NewPolyAccumulator(pk *bgn.PublicKey, dims int) *PolyAccumulator {
+	return &PolyAccumulator{
+		Pk:         pk,
+		Dims:       dims,
+		Sum:        make([]*bgn.PolyCiphertext, dims),
+		SumProduct: newPolyTriangle(dims),
+	}
+}
+
+// Add accumulates a single encrypted observation with one ciphertext per variable
+func (acc *Accumulator) Add(obs ...*bgn.Ciphertext) error {
+
+	if len(obs) != acc.Dims {
+		return errors.New("observation does not match the number of variables")
+	}
+
+	pk := acc.Pk
+
+	products := newTriangle(acc.Dims)
+	for i := 0; i < acc.Dims; i++ {
+		for j := i; j < acc.Dims; j++ {
+			products[i][j-i] = pk.Mult(obs[i], obs[j])
+		}
+	}
+
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	for i := 0; i < acc.Dims; i++ {
+		if acc.Sum[i] == nil {
+			acc.Sum[i] = obs[i]
+		} else {
+			acc.Sum[i] = pk.Add(acc.Sum[i], obs[i])
+		}
+
+		for k := range products[i] {
+			if acc.SumProduct[i][k] == nil {
+				acc.SumProduct[i][k] = products[i][k]
+			} else {
+				acc.SumProduct[i][k] = pk.Add(acc.SumProduct[i][k], products[i][k])
+			}
+		}
+	}
+
+	acc.Count++
+
+	return nil
+}
+
+// Add accumulates a single encrypted observation with one PolyCiphertext per variable
+func (acc *PolyAccumulator) Add(obs ...*bgn.PolyCiphertext) error {
+
+	if len(obs) != acc.Dims {
+		return errors.New("observation does not match the number of variables")
+	}
+
+	pk := acc.Pk
+
+	products := newPolyTriangle(acc.Dims)
+	for i := 0; i < acc.Dims; i++ {
+		for j := i; j < acc.Dims; j++ {
+			product, err := pk.MultPoly(obs[i], obs[j])
+			if err != nil {
+				return err
+			}
+			products[i][j-i] = product
+		}
+	}
+
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	// compute the new sums first so that an overflow leaves the accumulator untouched
+	sum := make([]*bgn.PolyCiphertext, acc.Dims)
+	sumProduct := newPolyTriangle(acc.Dims)
+
+	for i := 0; i < acc.Dims; i++ {
+		var err error
+		if sum[i], err = addPolyOrSet(pk, acc.Sum[i], obs[i]); err != nil {
+			return err
+		}
+
+		for k := range products[i] {
+			if sumProduct[i][k], err = addPolyOrSet(pk, acc.SumProduct[i][k], products[i][k]); err != nil {
+				return err
+			}
+		}
+	}
+
+	acc.Sum = sum
+	acc.SumProduct = sumProduct
+	acc.Count++
+
+	return nil
+}
+
+// addPolyOrSet returns acc + ct, or ct if nothing has been accumulated yet
+func addPolyOrSet(pk *bgn.PublicKey, acc *bgn.PolyCiphertext, ct *bgn.PolyCiphertext) (*bgn.PolyCiphertext, error) {
+
+	if acc == nil {
+		return ct, nil
+	}
+
+	return pk.AddPoly(acc, ct)
+}
+
+// Summary decrypts the accumulated quantities using the secret key and
+// derives the mean and covariance of every variable
+func (acc *Accumulator) Summary(sk *bgn.SecretKey) (*Summary, error) {
+
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	if acc.Count == 0 {
+		return nil, errors.New("no observations accumulated")
+	}
+
+	decrypt := func(ct *bgn.Ciphertext) (*big.Float, error) {
+		v, err := sk.Decrypt(ct, acc.Pk)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Float).SetInt(v), nil
+	}
+
+	sum := make([]*big.Float, acc.Dims)
+	sumProduct := make([][]*big.Float, acc.Dims)
+	for i := 0; i < acc.Dims; i++ {
+		sumProduct[i] = make([]*big.Float, acc.Dims)
+	}
+
+	for i := 0; i < acc.Dims; i++ {
+		v, err := decrypt(acc.Sum[i])
+		if err != nil {
+			return nil, err
+		}
+		sum[i] = v
+
+		for k, ct := range acc.SumProduct[i] {
+			v, err := decrypt(ct)
+			if err != nil {
+				return nil, err
+			}
+			sumProduct[i][i+k] = v
+			sumProduct[i+k][i] = v
+		}
+	}
+
+	return newSummary(acc.Count, sum, sumProduct), nil
+}
+
+// Summary decrypts the accumulated quantities using the secret key and
+// derives the mean and covariance of every variable
+func (acc *PolyAccumulator) Summary(sk *bgn.SecretKey) (*Summary, error) {
+
+	acc.mu.Lock()
+	defer acc.mu.Unlock()
+
+	if acc.Count == 0 {
+		return nil, errors.New("no observations accumulated")
+	}
+
+	decrypt := func(ct *bgn.PolyCiphertext) (*big.Float, error) {
+		pt, err := sk.DecryptPoly(ct, acc.Pk)
+		if err != nil {
+			return nil, err
+		}
+		return pt.PolyEval(), nil
+	}
+
+	sum := make([]*big.Float, acc.Dims)
+	sumProduct := make([][]*big.Float, acc.Dims)
+	for i := 0; i < acc.Dims; i++ {
+		sumProduct[i] = make([]*big.Float, acc.Dims)
+	}
+
+	for i := 0; i < acc.Dims; i++ {
+		v, err := decrypt(acc.Sum[i])
+		if err != nil {
+			return nil, err
+		}
+		sum[i] = v
+
+		for k, ct := range acc.SumProduct[i] {
+			v, err := decrypt(ct)
+			if err != nil {
+				return nil, err
+			}
+			sumProduct[i][i+k] = v
+			sumProduct[i+k][i] = v
+		}
+	}
+
+	return newSummary(acc.Count, sum, sumProduct), nil
+}
+
+// Variance returns the population variance of the i-th variable
+func (s *Summary) Variance(i int) *big.Float {
+	return s.Covariance[i][i]
+}
+
+// newSummary computes mean_i = sum_i / n and
+// cov_ij = sumProduct_ij / n - mean_i * mean_j
+func newSummary(count int, sum []*big.Float, sumProduct [][]*big.Float) *Summary {
+
+	n := new(big.Float).SetInt64(int64(count))
+	dims := len(sum)
+
+	mean := make([]*big.Float, dims)
+	for i := range sum {
+		mean[i] = new(big.Float).Quo(sum[i], n)
+	}
+
+	cov := make([][]*big.Float, dims)
+	for i := 0; i < dims; i++ {
+		cov[i] = make([]*big.Float, dims)
+		for j := 0; j < dims; j++ {
+			c := new(big.Float).Quo(sumProduct[i][j], n)
+			cov[i][j] = c.Sub(c, new(big.Float).Mul(mean[i], mean[j]))
+		}
+	}
+
+	return &Summary{count, sum, sumProduct, mean, cov}
+}
+
+// newTriangle allocates the upper triangle of a dims x dims matrix where
+// row i holds the entries (i, i), (i, i+1), ..., (i, dims-1)
+func newTriangle(dims int) [][]*bgn.Ciphertext {
+	res := make([][]*bgn.Ciphertext, dims)
+	for i := 0; i < dims; i++ {
+		res[i] = make([]*bgn.Ciphertext, dims-i)
+	}
+
+	return res
+}
+
+func newPolyTriangle(dims int) [][]*bgn.PolyCiphertext {
+	res := make([][]*bgn.PolyCiphertext, dims)
+	for i := 0; i < dims; i++ {
+		res[i] = make([]*bgn.PolyCiphertext, dims-i)
+	}
+
+	return res
+}
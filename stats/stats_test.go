@@ -0,0 +1,104 @@
+package stats
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/sachaservan/bgn"
+)
+
+const KEYBITS = 512
+const POLYBASE = 3
+const MSGSPACE = 1021
+const FPSCALEBASE = 3
+const FPPREC = 0.0001
+const DET = true // deterministic ops
+
+func TestAccumulatorSummary(t *testing.T) {
+
+	pk, sk, err := bgn.NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	pk.SetupDecryption(sk)
+
+	x := []int64{1, 2, 3, 4}
+	y := []int64{2, 1, 0, 3}
+
+	acc := NewAccumulator(pk, 2)
+	for i := range x {
+		err := acc.Add(pk.Encrypt(big.NewInt(x[i])), pk.Encrypt(big.NewInt(y[i])))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	s, err := acc.Summary(sk)
+	if err != nil {
+		t.Fatalf("Error when computing summary %v\n", err.Error())
+	}
+
+	// mean(x) = 2.5, var(x) = 1.25, mean(y) = 1.5, var(y) = 1.25, cov(x, y) = 0.25
+	checkFloat(t, "sum x", s.Sum[0], 10)
+	checkFloat(t, "sum xy", s.SumProduct[0][1], 16)
+	checkFloat(t, "mean x", s.Mean[0], 2.5)
+	checkFloat(t, "mean y", s.Mean[1], 1.5)
+	checkFloat(t, "var x", s.Variance(0), 1.25)
+	checkFloat(t, "var y", s.Variance(1), 1.25)
+	checkFloat(t, "cov xy", s.Covariance[0][1], 0.25)
+	checkFloat(t, "cov yx", s.Covariance[1][0], 0.25)
+}
+
+func TestPolyAccumulatorSummary(t *testing.T) {
+
+	pk, sk, err := bgn.NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	pk.SetupDecryption(sk)
+
+	x := []float64{1.5, 2.5, 3.0}
+
+	acc := NewPolyAccumulator(pk, 1)
+	for _, v := range x {
+		pt, err := pk.NewPolyPlaintext(big.NewFloat(v))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		err = acc.Add(pk.EncryptPoly(pt))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	s, err := acc.Summary(sk)
+	if err != nil {
+		t.Fatalf("Error when computing summary %v\n", err.Error())
+	}
+
+	// mean = 7/3, var = (2.25 + 6.25 + 9)/3 - 49/9 = 7/18
+	checkFloat(t, "sum", s.Sum[0], 7)
+	checkFloat(t, "mean", s.Mean[0], 7.0/3.0)
+	checkFloat(t, "var", s.Variance(0), 7.0/18.0)
+}
+
+func TestAccumulatorDimensionMismatch(t *testing.T) {
+
+	pk, _, err := bgn.NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	acc := NewAccumulator(pk, 2)
+	if err := acc.Add(pk.Encrypt(big.NewInt(1))); err == nil {
+		t.Fail()
+	}
+}
+
+func checkFloat(t *testing.T, name string, actual *big.Float, expected float64) {
+	if fmt.Sprintf("%.3f", actual) != fmt.Sprintf("%.3f", expected) {
+		t.Errorf("%s: expected %.3f got %.3f\n", name, expected, actual)
+	}
+}
@@ -0,0 +1,336 @@
+package bgn
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Nik-U/pbc"
+)
+
+// ThresholdParams are the public parameters of a t-out-of-n
+// sharing of the BGN secret key
+type ThresholdParams struct {
+	Threshold        int            // number of parties required to decrypt
+	Parties          int            // total number of parties
+	V                *pbc.Element   // base e(P, P) of the verification keys
+	VerificationKeys []*pbc.Element // VerificationKeys[i-1] = V^share_i
+
+	genGT *pbc.Element // e(P, P)^q1
+}
+
+// ThresholdKeyShare is the share of the secret key held by a single party
+type ThresholdKeyShare struct {
+	Index int      // party index in 1..n
+	Share *big.Int // f(Index) where f is a random polynomial with f(0) = q1
+}
+
+// PartialDecryption is a single party's contribution C^share to the
+// decryption of a ciphertext, where C = ct.C for level2 ciphertexts and
+// C = e(ct.C, P) for level1 ones. Partial decryptions are always in GT:
+// combining level1 partial decryptions in G1 would yield P^(q1*m), from
+// which anyone knowing m recovers P^q1 and can decrypt level1 ciphertexts alone
+type PartialDecryption struct {
+	Index int
+	D     *pbc.Element // C^share
+	L2    bool
+	Proof *PartialDecryptionProof
+}
+
+// PartialDecryptionProof is a Chaum-Pedersen proof that
+// log_V(VerificationKey) = log_C(D)
+type PartialDecryptionProof struct {
+	A *pbc.Element // V^k
+	B *pbc.Element // C^k
+	Z *big.Int     // k + c*share
+}
+
+// NewThresholdShares splits the secret key into n shares such that any t
+// of them suffice to decrypt, using Shamir secret sharing over Z_N
+func (sk *SecretKey) NewThresholdShares(pk *PublicKey, t int, n int) (*ThresholdParams, []*ThresholdKeyShare, error) {
+
+	if t < 1 || t > n {
+		return nil, nil, errors.New("threshold must be between 1 and the number of parties")
+	}
+
+	// f(x) = q1 + a_1 x + ... + a_{t-1} x^{t-1} mod N
+	coeffs := make([]*big.Int, t)
+	coeffs[0] = new(big.Int).Set(sk.Key)
+	for i := 1; i < t; i++ {
+		coeffs[i] = newCryptoRandom(pk.N)
+	}
+
+	pk.mu.Lock()
+	V := pk.Pairing.NewGT().Pair(pk.P, pk.P)
+	pk.mu.Unlock()
+
+	shares := make([]*ThresholdKeyShare, n)
+	vks := make([]*pbc.Element, n)
+
+	for i := 1; i <= n; i++ {
+		x := big.NewInt(int64(i))
+		s := big.NewInt(0)
+		for j := t - 1; j >= 0; j-- {
+			s.Mul(s, x)
+			s.Add(s, coeffs[j])
+			s.Mod(s, pk.N)
+		}
+
+		shares[i-1] = &ThresholdKeyShare{i, s}
+
+		vk := V.NewFieldElement()
+		vk.PowBig(V, s)
+		vks[i-1] = vk
+	}
+
+	tp := &ThresholdParams{
+		Threshold:        t,
+		Parties:          n,
+		V:                V,
+		VerificationKeys: vks,
+	}
+
+	return tp, shares, nil
+}
+
+// NewPartialDecryption computes the party's partial decryption of ct
+// along with a proof that it was computed using the party's key share
+func (pk *PublicKey) NewPartialDecryption(tp *ThresholdParams, share *ThresholdKeyShare, ct *Ciphertext) *PartialDecryption {
+
+	C := pk.partialDecryptionBase(ct)
+
+	D := C.NewFieldElement()
+	D.PowBig(C, share.Share)
+
+	k := newCryptoRandom(pk.N)
+
+	A := tp.V.NewFieldElement()
+	A.PowBig(tp.V, k)
+
+	B := C.NewFieldElement()
+	B.PowBig(C, k)
+
+	proof := &PartialDecryptionProof{A, B, nil}
+	c := pk.partialDecryptionChallenge(tp.VerificationKeys[share.Index-1], ct, D, proof)
+
+	Z := new(big.Int).Mul(c, share.Share)
+	Z.Add(Z, k)
+	Z.Mod(Z, pk.N)
+	proof.Z = Z
+
+	return &PartialDecryption{share.Index, D, ct.L2, proof}
+}
+
+// VerifyPartialDecryption outputs true if pd is a valid partial decryption of ct
+func (pk *PublicKey) VerifyPartialDecryption(tp *ThresholdParams, ct *Ciphertext, pd *PartialDecryption) bool {
+
+	if pd == nil || pd.D == nil || pd.Proof == nil || pd.Proof.Z == nil {
+		return false
+	}
+
+	if pd.Index < 1 || pd.Index > tp.Parties || pd.L2 != ct.L2 {
+		return false
+	}
+
+	vk := tp.VerificationKeys[pd.Index-1]
+	c := pk.partialDecryptionChallenge(vk, ct, pd.D, pd.Proof)
+
+	// V^z = A * vk^c
+	lhs := tp.V.NewFieldElement()
+	lhs.PowBig(tp.V, pd.Proof.Z)
+	rhs := vk.NewFieldElement()
+	rhs.PowBig(vk, c)
+	rhs.Mul(rhs, pd.Proof.A)
+
+	if !lhs.Equals(rhs) {
+		return false
+	}
+
+	// C^z = B * D^c
+	C := pk.partialDecryptionBase(ct)
+	lhs = C.NewFieldElement()
+	lhs.PowBig(C, pd.Proof.Z)
+	rhs = pd.D.NewFieldElement()
+	rhs.PowBig(pd.D, c)
+	rhs.Mul(rhs, pd.Proof.B)
+
+	return lhs.Equals(rhs)
+}
+
+// CombinePartialDecryptions verifies the partial decryptions of ct and
+// combines t of them into C^q1 in GT using Lagrange interpolation in the exponent.
+// Invalid partial decryptions are skipped so that a single misbehaving party
+// cannot block decryption; fails if fewer than t valid ones remain
+func (pk *PublicKey) CombinePartialDecryptions(tp *ThresholdParams, ct *Ciphertext, partials []*PartialDecryption) (*pbc.Element, error) {
+
+	used := make(map[int]bool)
+	valid := make([]*PartialDecryption, 0, tp.Threshold)
+	invalid := make([]int, 0)
+
+	for _, pd := range partials {
+		if len(valid) == tp.Threshold {
+			break
+		}
+
+		if !pk.VerifyPartialDecryption(tp, ct, pd) {
+			if pd != nil {
+				invalid = append(invalid, pd.Index)
+			}
+			continue
+		}
+
+		if used[pd.Index] {
+			continue
+		}
+		used[pd.Index] = true
+
+		valid = append(valid, pd)
+	}
+
+	if len(valid) < tp.Threshold {
+		if len(invalid) > 0 {
+			return nil, fmt.Errorf("not enough valid partial decryptions (invalid from parties %v)", invalid)
+		}
+		return nil, errors.New("not enough partial decryptions")
+	}
+
+	return interpolateInExponent(valid, pk.N)
+}
+
+// SetupThresholdDecryption computes the level2 decryption base e(P, P)^q1
+// from the public verification keys and the tables necessary for recovering
+// messages from combined partial decryptions. Level1 ciphertexts are also
+// decrypted in GT, so no level1 base P^q1 is ever reconstructed
+func (pk *PublicKey) SetupThresholdDecryption(tp *ThresholdParams) error {
+
+	partials := make([]*PartialDecryption, tp.Threshold)
+	for i := 0; i < tp.Threshold; i++ {
+		partials[i] = &PartialDecryption{Index: i + 1, D: tp.VerificationKeys[i], L2: true}
+	}
+
+	genGT, err := interpolateInExponent(partials, pk.N)
+	if err != nil {
+		return err
+	}
+
+	tp.genGT = genGT
+	pk.precomputeTableGT(genGT)
+
+	return nil
+}
+
+// ThresholdDecrypt recovers the value encrypted by ct from at least t
+// valid partial decryptions. Requires SetupThresholdDecryption
+func (pk *PublicKey) ThresholdDecrypt(tp *ThresholdParams, ct *Ciphertext, partials []*PartialDecryption) (*big.Int, error) {
+
+	if tp.genGT == nil {
+		return nil, errors.New("threshold decryption has not been set up")
+	}
+
+	csk, err := pk.CombinePartialDecryptions(tp, ct, partials)
+	if err != nil {
+		return nil, err
+	}
+
+	// csk = e(P, P)^(q1*m) at both levels
+	gsk := tp.genGT
+
+	pt, err := pk.recoverMessage(gsk, csk, true, 1)
+	if err == nil {
+		return pt, nil
+	}
+
+	// the ciphertext may encode a negative value
+	inv := csk.NewFieldElement()
+	inv.Invert(csk)
+
+	pt, err = pk.recoverMessage(gsk, inv, true, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return pt.Neg(pt), nil
+}
+
+// partialDecryptionBase returns the element partial decryptions of ct are
+// computed on: ct.C for level2 ciphertexts and e(ct.C, P) for level1 ones
+func (pk *PublicKey) partialDecryptionBase(ct *Ciphertext) *pbc.Element {
+
+	if ct.L2 {
+		return ct.C
+	}
+
+	pk.mu.Lock()
+	defer pk.mu.Unlock()
+
+	return pk.Pairing.NewGT().Pair(ct.C, pk.P)
+}
+
+// interpolateInExponent computes prod_i D_i^lambda_i which equals
+// C^f(0) when each D_i = C^f(i)
+func interpolateInExponent(partials []*PartialDecryption, n *big.Int) (*pbc.Element, error) {
+
+	indices := make([]int, len(partials))
+	for i, pd := range partials {
+		indices[i] = pd.Index
+	}
+
+	res := partials[0].D.NewFieldElement()
+	for i, pd := range partials {
+		lambda, err := lagrangeCoefficient(indices, i, n)
+		if err != nil {
+			return nil, err
+		}
+
+		term := pd.D.NewFieldElement()
+		term.PowBig(pd.D, lambda)
+
+		if i == 0 {
+			res.Set(term)
+		} else {
+			res.Mul(res, term)
+		}
+	}
+
+	return res, nil
+}
+
+// lagrangeCoefficient computes prod_{j != i} x_j / (x_j - x_i) mod n
+func lagrangeCoefficient(indices []int, i int, n *big.Int) (*big.Int, error) {
+
+	num := big.NewInt(1)
+	den := big.NewInt(1)
+	xi := big.NewInt(int64(indices[i]))
+
+	for j, idx := range indices {
+		if j == i {
+			continue
+		}
+
+		xj := big.NewInt(int64(idx))
+		num.Mul(num, xj)
+		den.Mul(den, new(big.Int).Sub(xj, xi))
+	}
+
+	den.Mod(den, n)
+	inv := new(big.Int).ModInverse(den, n)
+	if inv == nil {
+		return nil, errors.New("lagrange coefficient is not invertible")
+	}
+
+	num.Mul(num, inv)
+	return num.Mod(num, n), nil
+}
+
+// partialDecryptionChallenge computes the Fiat-Shamir challenge
+// of the partial decryption proof
+func (pk *PublicKey) partialDecryptionChallenge(vk *pbc.Element, ct *Ciphertext, D *pbc.Element, proof *PartialDecryptionProof) *big.Int {
+
+	t := pk.newTranscript("bgn/partial-decryption")
+	t.AppendElement("vk", vk)
+	t.AppendCiphertext("ct", ct)
+	t.AppendElement("D", D)
+	t.AppendElement("A", proof.A)
+	t.AppendElement("B", proof.B)
+	return t.Challenge("c", pk.N)
+}
@@ -0,0 +1,153 @@
+package bgn
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+// party simulates a single key share holder
+type party struct {
+	share *ThresholdKeyShare
+}
+
+func (p *party) partialDecrypt(pk *PublicKey, tp *ThresholdParams, ct *Ciphertext) *PartialDecryption {
+	return pk.NewPartialDecryption(tp, p.share, ct)
+}
+
+func newParties(t *testing.T, threshold int, n int) (*PublicKey, *ThresholdParams, []*party) {
+
+	pk, sk, err := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	tp, shares, err := sk.NewThresholdShares(pk, threshold, n)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	parties := make([]*party, n)
+	for i, share := range shares {
+		parties[i] = &party{share}
+	}
+
+	// the dealer's key is discarded; decryption is set up from public values
+	if err := pk.SetupThresholdDecryption(tp); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	return pk, tp, parties
+}
+
+func TestThresholdDecrypt(t *testing.T) {
+
+	pk, tp, parties := newParties(t, 3, 5)
+
+	c1 := pk.Encrypt(big.NewInt(12))
+	c2 := pk.Encrypt(big.NewInt(-3))
+	cts := []*Ciphertext{c1, c2, pk.Add(c1, c2), pk.Mult(c1, c2)}
+	expected := []int64{12, -3, 9, -36}
+
+	// every subset of exactly t parties must be able to decrypt
+	subsets := [][]int{{0, 1, 2}, {0, 2, 4}, {1, 3, 4}, {4, 3, 2}}
+
+	for i, ct := range cts {
+		for _, subset := range subsets {
+			partials := make([]*PartialDecryption, 0)
+			for _, j := range subset {
+				partials = append(partials, parties[j].partialDecrypt(pk, tp, ct))
+			}
+
+			actual, err := pk.ThresholdDecrypt(tp, ct, partials)
+			if err != nil {
+				t.Fatalf("Error when decrypting with parties %v: %v\n", subset, err.Error())
+			}
+
+			if actual.Int64() != expected[i] {
+				t.Errorf("Parties %v: expected %d got %v\n", subset, expected[i], actual)
+			}
+		}
+	}
+}
+
+func TestThresholdCombineLevel1(t *testing.T) {
+
+	pk, tp, parties := newParties(t, 2, 3)
+
+	ct := pk.Encrypt(big.NewInt(12))
+	partials := []*PartialDecryption{
+		parties[0].partialDecrypt(pk, tp, ct),
+		parties[2].partialDecrypt(pk, tp, ct),
+	}
+
+	combined, err := pk.CombinePartialDecryptions(tp, ct, partials)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// the combined value must be e(P, P)^(q1*m) rather than P^(q1*m),
+	// which would reveal P^q1 to anyone knowing m
+	expected := pk.DecryptionVK.NewFieldElement()
+	expected.PowBig(pk.DecryptionVK, big.NewInt(12))
+
+	if !bytes.Equal(combined.Bytes(), expected.Bytes()) {
+		t.Errorf("Level1 partial decryptions were not combined in GT\n")
+	}
+}
+
+func TestThresholdDecryptNotEnoughParties(t *testing.T) {
+
+	pk, tp, parties := newParties(t, 3, 5)
+
+	ct := pk.Encrypt(big.NewInt(5))
+	partials := []*PartialDecryption{
+		parties[0].partialDecrypt(pk, tp, ct),
+		parties[1].partialDecrypt(pk, tp, ct),
+		parties[1].partialDecrypt(pk, tp, ct), // duplicates don't count
+	}
+
+	if _, err := pk.ThresholdDecrypt(tp, ct, partials); err == nil {
+		t.Fail()
+	}
+}
+
+func TestPartialDecryptionProofBad(t *testing.T) {
+
+	pk, tp, parties := newParties(t, 2, 3)
+
+	ct := pk.Encrypt(big.NewInt(5))
+	pd := parties[0].partialDecrypt(pk, tp, ct)
+
+	if !pk.VerifyPartialDecryption(tp, ct, pd) {
+		t.Fatalf("Valid partial decryption rejected\n")
+	}
+
+	// partial decryption claimed by the wrong party
+	forged := &PartialDecryption{2, pd.D, pd.L2, pd.Proof}
+	if pk.VerifyPartialDecryption(tp, ct, forged) {
+		t.Errorf("Partial decryption with wrong index accepted\n")
+	}
+
+	// partial decryption computed with a wrong share
+	bad := pk.NewPartialDecryption(tp, &ThresholdKeyShare{1, big.NewInt(42)}, ct)
+	if pk.VerifyPartialDecryption(tp, ct, bad) {
+		t.Errorf("Partial decryption with wrong share accepted\n")
+	}
+
+	partials := []*PartialDecryption{bad, parties[1].partialDecrypt(pk, tp, ct)}
+	if _, err := pk.ThresholdDecrypt(tp, ct, partials); err == nil {
+		t.Errorf("Decryption with invalid partial decryption succeeded\n")
+	}
+
+	// invalid and missing partial decryptions are skipped
+	partials = []*PartialDecryption{nil, bad, forged, parties[1].partialDecrypt(pk, tp, ct), parties[2].partialDecrypt(pk, tp, ct)}
+	actual, err := pk.ThresholdDecrypt(tp, ct, partials)
+	if err != nil {
+		t.Fatalf("Error when decrypting with invalid partial decryptions: %v\n", err)
+	}
+
+	if actual.Int64() != 5 {
+		t.Errorf("Expected 5 got %v\n", actual)
+	}
+}
@@ -0,0 +1,105 @@
+package bgn
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/Nik-U/pbc"
+)
+
+// Transcript is a Fiat-Shamir transcript in the style of Merlin.
+// Every message is appended with a label and its length so that
+// distinct sequences of messages never hash to the same state.
+// Transcripts start with a protocol label and the public key, which
+// prevents proofs from being replayed across protocols or keys
+type Transcript struct {
+	state []byte
+}
+
+// NewTranscript creates a transcript for the given protocol
+func NewTranscript(protocol string) *Transcript {
+	t := &Transcript{}
+	t.AppendMessage("protocol", []byte(protocol))
+	return t
+}
+
+// newTranscript creates a transcript for the given protocol bound to the public key
+func (pk *PublicKey) newTranscript(protocol string) *Transcript {
+	t := NewTranscript(protocol)
+	t.AppendPublicKey(pk)
+	return t
+}
+
+// AppendMessage appends labeled data to the transcript
+func (t *Transcript) AppendMessage(label string, data []byte) {
+	t.appendBytes([]byte(label))
+	t.appendBytes(data)
+}
+
+// AppendElement appends a labeled group element to the transcript
+func (t *Transcript) AppendElement(label string, e *pbc.Element) {
+	t.AppendMessage(label, e.Bytes())
+}
+
+// AppendInt appends a labeled (possibly negative) integer to the transcript
+func (t *Transcript) AppendInt(label string, x *big.Int) {
+	sign := []byte{0}
+	if x.Sign() < 0 {
+		sign[0] = 1
+	}
+	t.AppendMessage(label, append(sign, x.Bytes()...))
+}
+
+// AppendCiphertext appends a labeled ciphertext (including its level) to the transcript
+func (t *Transcript) AppendCiphertext(label string, ct *Ciphertext) {
+	level := []byte{1}
+	if ct.L2 {
+		level[0] = 2
+	}
+	t.AppendMessage(label, append(level, ct.C.Bytes()...))
+}
+
+// AppendPublicKey appends the public parameters of the key to the transcript
+func (t *Transcript) AppendPublicKey(pk *PublicKey) {
+	t.AppendMessage("pk.N", pk.N.Bytes())
+	t.AppendElement("pk.P", pk.P)
+	t.AppendElement("pk.Q", pk.Q)
+}
+
+// Challenge derives a labeled challenge in [0, mod) from the transcript.
+// The challenge is appended to the transcript so that subsequent
+// challenges depend on it
+func (t *Transcript) Challenge(label string, mod *big.Int) *big.Int {
+
+	t.AppendMessage("challenge", []byte(label))
+
+	// expand the state to 128 bits more than the modulus to make
+	// the bias of the reduction negligible
+	size := (mod.BitLen()+128+7)/8 + 1
+	out := make([]byte, 0, size+sha256.Size)
+	for ctr := uint32(0); len(out) < size; ctr++ {
+		h := sha256.New()
+		h.Write(t.state)
+
+		buf := make([]byte, 4)
+		binary.BigEndian.PutUint32(buf, ctr)
+		h.Write(buf)
+
+		out = h.Sum(out)
+	}
+
+	c := new(big.Int).SetBytes(out[:size])
+	c.Mod(c, mod)
+
+	t.AppendMessage(label, c.Bytes())
+
+	return c
+}
+
+func (t *Transcript) appendBytes(data []byte) {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(len(data)))
+	t.state = append(t.state, buf...)
+	t.state = append(t.state, data...)
+}
@@ -0,0 +1,69 @@
+package bgn
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestTranscriptDeterministic(t *testing.T) {
+
+	mod := big.NewInt(1000003)
+
+	t1 := NewTranscript("test")
+	t1.AppendMessage("a", []byte("hello"))
+	t2 := NewTranscript("test")
+	t2.AppendMessage("a", []byte("hello"))
+
+	c1 := t1.Challenge("c", mod)
+	c2 := t2.Challenge("c", mod)
+	if c1.Cmp(c2) != 0 {
+		t.Fatalf("Identical transcripts produced different challenges")
+	}
+
+	if c1.Sign() < 0 || c1.Cmp(mod) >= 0 {
+		t.Errorf("Challenge %v not reduced mod %v", c1, mod)
+	}
+
+	// subsequent challenges depend on the previous ones
+	if t1.Challenge("c", mod).Cmp(c1) == 0 {
+		t.Errorf("Repeated challenge did not change")
+	}
+}
+
+func TestTranscriptDomainSeparation(t *testing.T) {
+
+	mod := new(big.Int).Lsh(big.NewInt(1), 256)
+
+	challenge := func(protocol string, label string, data string) *big.Int {
+		tr := NewTranscript(protocol)
+		tr.AppendMessage(label, []byte(data))
+		return tr.Challenge("c", mod)
+	}
+
+	base := challenge("p1", "a", "bc")
+
+	if base.Cmp(challenge("p2", "a", "bc")) == 0 {
+		t.Errorf("Different protocols produced the same challenge")
+	}
+
+	// moving bytes between the label and the data must change the challenge
+	if base.Cmp(challenge("p1", "ab", "c")) == 0 {
+		t.Errorf("Ambiguous encoding produced the same challenge")
+	}
+}
+
+func TestProofDoesNotTransferAcrossKeys(t *testing.T) {
+
+	pk, _, _ := NewKeyGen(KEYBITS, big.NewInt(MSGSPACE), POLYBASE, FPSCALEBASE, FPPREC, DET)
+
+	v := big.NewInt(1)
+	r := newCryptoRandom(pk.N)
+	ct := pk.EncryptWithRandomness(v, r)
+	proof := pk.NewBitProof(v, r)
+
+	// same group and ciphertext but a different Q
+	other := &PublicKey{G1: pk.G1, P: pk.P, Q: pk.P, N: pk.N, Pairing: pk.Pairing}
+	if other.VerifyBitProof(ct, proof) {
+		t.Errorf("Proof accepted under a different public key")
+	}
+}